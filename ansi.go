@@ -0,0 +1,177 @@
+/*
+   Copyright (c) 2020, btnmasher
+   All rights reserved.
+
+   Redistribution and use in source and binary forms, with or without modification, are permitted provided that
+   the following conditions are met:
+
+   1. Redistributions of source code must retain the above copyright notice, this list of conditions and the
+      following disclaimer.
+
+   2. Redistributions in binary form must reproduce the above copyright notice, this list of conditions and
+      the following disclaimer in the documentation and/or other materials provided with the distribution.
+
+   3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse or
+      promote products derived from this software without specific prior written permission.
+
+   THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED
+   WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+   PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR
+   ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+   TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+   HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+   NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+   POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package util
+
+import "unicode/utf8"
+
+// ansiLen returns the length in bytes of the ANSI escape sequence starting at s[i],
+// or zero if there isn't one. This covers CSI sequences such as colors and cursor
+// movement, OSC and other string sequences, and two byte escapes. An unterminated
+// sequence is only its introducer, so the text after it is still counted.
+func ansiLen(s string, i int) int {
+	if s[i] != 0x1b {
+		return 0
+	}
+	if i+1 == len(s) {
+		return 1
+	}
+
+	switch s[i+1] {
+	case '[': // CSI: parameter and intermediate bytes, then a final byte.
+		for j := i + 2; j < len(s); j++ {
+			if s[j] >= 0x40 && s[j] <= 0x7e {
+				return j + 1 - i
+			}
+		}
+		return 2 // Unterminated.
+	case ']', 'P', 'X', '^', '_': // OSC, DCS, SOS, PM and APC: terminated by BEL or ST.
+		for j := i + 2; j < len(s); j++ {
+			if s[j] == 0x07 {
+				return j + 1 - i
+			}
+			if s[j] == 0x1b && j+1 < len(s) && s[j+1] == '\\' {
+				return j + 2 - i
+			}
+		}
+		return 2 // Unterminated.
+	default:
+		return 2
+	}
+}
+
+// ansiUnitLen returns the length in bytes of the ANSI escape sequence or rune
+// starting at s[i], which the ANSI-aware helpers never split.
+func ansiUnitLen(s string, i int) int {
+	if n := ansiLen(s, i); n > 0 {
+		return n
+	}
+	return runeLenAt(s, i)
+}
+
+// visibleMeasure measures text with VisibleLength, keeping escape sequences whole.
+var visibleMeasure = textMeasure{length: VisibleLength, unit: ansiUnitLen}
+
+// VisibleLength returns the number of runes in s, not counting ANSI escape sequences,
+// which is how wide colored text is on a terminal.
+func VisibleLength(s string) int {
+	length := 0
+	for i := 0; i < len(s); {
+		if n := ansiLen(s, i); n > 0 {
+			i += n
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+		length++
+	}
+	return length
+}
+
+// StripANSI returns s with its ANSI escape sequences, such as colors and cursor
+// movement, removed, for writing colored output to plain files.
+func StripANSI(s string) string {
+	var out []byte
+	last := 0 // Start of the text not yet copied to out.
+
+	for i := 0; i < len(s); {
+		n := ansiLen(s, i)
+		if n == 0 {
+			i++
+			continue
+		}
+		out = append(out, s[last:i]...)
+		i += n
+		last = i
+	}
+
+	if last == 0 {
+		return s // Nothing to strip.
+	}
+	return string(append(out, s[last:]...))
+}
+
+// WithVisibleLength measures the maxlength with VisibleLength, so ANSI escape
+// sequences don't count towards it.
+func WithVisibleLength() ChunkOption {
+	return func(c *chunkConfig) {
+		c.measure = visibleMeasure
+	}
+}
+
+// ChunkStringVisible is the same as ChunkString, but measures the pieces with
+// VisibleLength, so ANSI escape sequences don't count towards the maxlength.
+func ChunkStringVisible(s string, maxlength int, boundary func(rune) bool) []string {
+	return chunkString(s, maxlength, boundary, visibleMeasure)
+}
+
+// WrapTextVisible is the same as WrapText, but measures the text with VisibleLength,
+// so ANSI escape sequences don't count towards the width.
+func WrapTextVisible(s string, width int, indent, subsequentIndent string) string {
+	return wrapText(s, width, indent, subsequentIndent, visibleMeasure)
+}
+
+// TruncateVisible is the same as Truncate, but counts max with VisibleLength.
+// ANSI escape sequences in the part which is cut off are kept after the suffix,
+// so a color reset at the end of s still takes effect.
+func TruncateVisible(s string, max int, suffix string) string {
+	if max < 0 {
+		max = 0
+	}
+
+	runes := 0
+	for i := 0; i < len(s); {
+		if n := ansiLen(s, i); n > 0 {
+			i += n
+			continue
+		}
+
+		if runes == max {
+			return s[:i] + suffix + ansiSequences(s[i:])
+		}
+
+		_, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+		runes++
+	}
+
+	return s
+}
+
+// ansiSequences returns the ANSI escape sequences in s, without the text between them.
+func ansiSequences(s string) string {
+	var seqs []byte
+	for i := 0; i < len(s); {
+		n := ansiLen(s, i)
+		if n == 0 {
+			i++
+			continue
+		}
+		seqs = append(seqs, s[i:i+n]...)
+		i += n
+	}
+	return string(seqs)
+}
@@ -0,0 +1,109 @@
+/*
+   Copyright (c) 2020, btnmasher
+   All rights reserved.
+
+   Redistribution and use in source and binary forms, with or without modification, are permitted provided that
+   the following conditions are met:
+
+   1. Redistributions of source code must retain the above copyright notice, this list of conditions and the
+      following disclaimer.
+
+   2. Redistributions in binary form must reproduce the above copyright notice, this list of conditions and
+      the following disclaimer in the documentation and/or other materials provided with the distribution.
+
+   3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse or
+      promote products derived from this software without specific prior written permission.
+
+   THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED
+   WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+   PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR
+   ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+   TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+   HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+   NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+   POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package util
+
+// Arena is a bump allocator which hands out many small byte slices carved from a few
+// large blocks, and releases them all at once with Free. It suits request-scoped
+// work such as parsing, where allocating each small slice separately would put
+// pressure on the garbage collector. An Arena is not safe for concurrent use.
+type Arena struct {
+	pool      *BytePool
+	blockSize int
+	blocks    [][]byte // Every block taken, so Free can return them.
+	cur       []byte   // Unused remainder of the current block.
+}
+
+// NewArena creates a new Arena which allocates from blocks of blockSize bytes. If pool
+// is not nil the blocks are taken from it and returned to it by Free, so that blocks
+// are reused across arenas; blockSize should then be one of the pool's size classes.
+func NewArena(blockSize int, pool *BytePool) *Arena {
+	if blockSize < 1 {
+		blockSize = 1
+	}
+
+	return &Arena{
+		pool:      pool,
+		blockSize: blockSize,
+	}
+}
+
+// block takes a new block of at least size bytes.
+func (a *Arena) block(size int) []byte {
+	var b []byte
+	if a.pool != nil {
+		b = a.pool.Get(size)
+	} else {
+		b = make([]byte, size)
+	}
+
+	a.blocks = append(a.blocks, b)
+	return b
+}
+
+// Alloc returns a zeroed slice of n bytes which stays valid until Free is called. Its
+// capacity is exactly n, so appending to it reallocates rather than spilling into
+// other allocations. Requests larger than a quarter of the block size get a block
+// of their own, so they don't waste the remainder of the current one.
+func (a *Arena) Alloc(n int) []byte {
+	if n > a.blockSize/4 {
+		b := a.block(n)
+		clear(b)
+		return b[:n:n]
+	}
+
+	if n > len(a.cur) {
+		a.cur = a.block(a.blockSize)
+	}
+
+	b := a.cur[:n:n]
+	a.cur = a.cur[n:]
+
+	clear(b)
+	return b
+}
+
+// Copy returns a copy of b allocated from the arena.
+func (a *Arena) Copy(b []byte) []byte {
+	c := a.Alloc(len(b))
+	copy(c, b)
+	return c
+}
+
+// Free releases every slice allocated from the arena at once, returning the blocks
+// to the pool if there is one. None of the slices may be used afterwards. The arena
+// itself can be reused.
+func (a *Arena) Free() {
+	if a.pool != nil {
+		for _, b := range a.blocks {
+			a.pool.Put(b)
+		}
+	}
+
+	clear(a.blocks)
+	a.blocks = a.blocks[:0]
+	a.cur = nil
+}
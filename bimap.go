@@ -0,0 +1,193 @@
+/*
+   Copyright (c) 2020, btnmasher
+   All rights reserved.
+
+   Redistribution and use in source and binary forms, with or without modification, are permitted provided that
+   the following conditions are met:
+
+   1. Redistributions of source code must retain the above copyright notice, this list of conditions and the
+      following disclaimer.
+
+   2. Redistributions in binary form must reproduce the above copyright notice, this list of conditions and
+      the following disclaimer in the documentation and/or other materials provided with the distribution.
+
+   3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse or
+      promote products derived from this software without specific prior written permission.
+
+   THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED
+   WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+   PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR
+   ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+   TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+   HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+   NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+   POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package util
+
+import "sync"
+
+// ConcurrentBiMap is a one-to-one mapping between string keys and string values,
+// indexed in both directions and kept in lockstep under a single lock.
+type ConcurrentBiMap struct {
+	forward map[string]string
+	reverse map[string]string
+	sync.RWMutex
+}
+
+// NewConcurrentBiMap initializes and returns a pointer to a new ConcurrentBiMap instance.
+func NewConcurrentBiMap() *ConcurrentBiMap {
+	m := &ConcurrentBiMap{
+		forward: make(map[string]string),
+		reverse: make(map[string]string),
+	}
+	return m
+}
+
+// ForEach will call the provided function for each key/value pair in the ConcurrentBiMap
+func (m *ConcurrentBiMap) ForEach(do func(string, string)) {
+	m.RLock()
+	defer m.RUnlock()
+
+	for key, val := range m.forward {
+		do(key, val)
+	}
+}
+
+// Length returns the number of key/value pairs in the map.
+func (m *ConcurrentBiMap) Length() int {
+	m.RLock()
+	defer m.RUnlock()
+
+	return len(m.forward)
+}
+
+// Add is used to add a key/value pair to the map.
+// Returns an error if either the key or the value already exists.
+func (m *ConcurrentBiMap) Add(key string, value string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	if _, exists := m.forward[key]; exists {
+		return &KeyError{Op: "ConcurrentBiMap: Cannot add map entry", Key: key, Err: ErrKeyExists}
+	}
+
+	if _, exists := m.reverse[value]; exists {
+		return &KeyError{Op: "ConcurrentBiMap: Cannot add map entry", Key: value, Err: ErrValueExists}
+	}
+
+	m.forward[key] = value
+	m.reverse[value] = key
+
+	return nil
+}
+
+// Set is used to change the value of an existing key in the map.
+// Returns an error if the key does not exist, or if the value belongs to another key.
+func (m *ConcurrentBiMap) Set(key string, value string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	old, exists := m.forward[key]
+
+	if !exists {
+		return &KeyError{Op: "ConcurrentBiMap: Cannot set map value", Key: key, Err: ErrKeyNotFound}
+	}
+
+	if owner, taken := m.reverse[value]; taken && owner != key {
+		return &KeyError{Op: "ConcurrentBiMap: Cannot set map value", Key: value, Err: ErrValueExists}
+	}
+
+	delete(m.reverse, old)
+	m.forward[key] = value
+	m.reverse[value] = key
+
+	return nil
+}
+
+// DelByKey is used to remove a key/value pair from the map by its key.
+// Returns an error if the key does not exist.
+func (m *ConcurrentBiMap) DelByKey(key string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	value, exists := m.forward[key]
+
+	if !exists {
+		return &KeyError{Op: "ConcurrentBiMap: Cannot delete map entry", Key: key, Err: ErrKeyNotFound}
+	}
+
+	delete(m.forward, key)
+	delete(m.reverse, value)
+
+	return nil
+}
+
+// DelByValue is used to remove a key/value pair from the map by its value.
+// Returns an error if the value does not exist.
+func (m *ConcurrentBiMap) DelByValue(value string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	key, exists := m.reverse[value]
+
+	if !exists {
+		return &KeyError{Op: "ConcurrentBiMap: Cannot delete map entry", Key: value, Err: ErrValueNotFound}
+	}
+
+	delete(m.forward, key)
+	delete(m.reverse, value)
+
+	return nil
+}
+
+// GetByKey is used to get the value paired with a key.
+// Returns an error if the key does not exist.
+func (m *ConcurrentBiMap) GetByKey(key string) (string, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	value, exists := m.forward[key]
+
+	if !exists {
+		return "", &KeyError{Op: "ConcurrentBiMap: Cannot get map value", Key: key, Err: ErrKeyNotFound}
+	}
+
+	return value, nil
+}
+
+// GetByValue is used to get the key paired with a value.
+// Returns an error if the value does not exist.
+func (m *ConcurrentBiMap) GetByValue(value string) (string, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	key, exists := m.reverse[value]
+
+	if !exists {
+		return "", &KeyError{Op: "ConcurrentBiMap: Cannot get map key", Key: value, Err: ErrValueNotFound}
+	}
+
+	return key, nil
+}
+
+// ExistsKey is used by external callers to check if a key
+// exists in the map and returns a boolean with the result.
+func (m *ConcurrentBiMap) ExistsKey(key string) bool {
+	m.RLock()
+	defer m.RUnlock()
+
+	_, exists := m.forward[key]
+	return exists
+}
+
+// ExistsValue is used by external callers to check if a value
+// exists in the map and returns a boolean with the result.
+func (m *ConcurrentBiMap) ExistsValue(value string) bool {
+	m.RLock()
+	defer m.RUnlock()
+
+	_, exists := m.reverse[value]
+	return exists
+}
@@ -0,0 +1,91 @@
+/*
+   Copyright (c) 2020, btnmasher
+   All rights reserved.
+
+   Redistribution and use in source and binary forms, with or without modification, are permitted provided that
+   the following conditions are met:
+
+   1. Redistributions of source code must retain the above copyright notice, this list of conditions and the
+      following disclaimer.
+
+   2. Redistributions in binary form must reproduce the above copyright notice, this list of conditions and
+      the following disclaimer in the documentation and/or other materials provided with the distribution.
+
+   3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse or
+      promote products derived from this software without specific prior written permission.
+
+   THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED
+   WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+   PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR
+   ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+   TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+   HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+   NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+   POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package util
+
+import (
+	"bytes"
+	"sync"
+	"time"
+)
+
+// idleTracker records when each Buffer waiting in a pool was recycled, so Buffers
+// left idle for too long can be evicted as they are taken from the pool. A nil
+// *idleTracker is valid and tracks nothing, which is how idle eviction is disabled.
+type idleTracker struct {
+	timeout time.Duration
+
+	mu    sync.Mutex
+	since map[*bytes.Buffer]time.Time
+}
+
+// newIdleTracker returns a tracker evicting Buffers idle for longer than timeout,
+// or nil if timeout is not positive.
+func newIdleTracker(timeout time.Duration) *idleTracker {
+	if timeout <= 0 {
+		return nil
+	}
+
+	return &idleTracker{
+		timeout: timeout,
+		since:   make(map[*bytes.Buffer]time.Time),
+	}
+}
+
+// stamp records that a Buffer is about to enter the pool.
+func (t *idleTracker) stamp(buf *bytes.Buffer) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	t.since[buf] = time.Now()
+	t.mu.Unlock()
+}
+
+// expired reports whether a Buffer has been idle for longer than the timeout.
+func (t *idleTracker) expired(buf *bytes.Buffer) bool {
+	if t == nil {
+		return false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	since, ok := t.since[buf]
+	return ok && time.Since(since) > t.timeout
+}
+
+// forget stops tracking a Buffer.
+func (t *idleTracker) forget(buf *bytes.Buffer) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	delete(t.since, buf)
+	t.mu.Unlock()
+}
@@ -0,0 +1,193 @@
+/*
+   Copyright (c) 2020, btnmasher
+   All rights reserved.
+
+   Redistribution and use in source and binary forms, with or without modification, are permitted provided that
+   the following conditions are met:
+
+   1. Redistributions of source code must retain the above copyright notice, this list of conditions and the
+      following disclaimer.
+
+   2. Redistributions in binary form must reproduce the above copyright notice, this list of conditions and
+      the following disclaimer in the documentation and/or other materials provided with the distribution.
+
+   3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse or
+      promote products derived from this software without specific prior written permission.
+
+   THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED
+   WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+   PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR
+   ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+   TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+   HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+   NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+   POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package util
+
+import (
+	"bytes"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+)
+
+// BufferLeak describes a Buffer which was taken from a pool and garbage collected
+// without ever being recycled.
+type BufferLeak struct {
+	Stack      string    // Stack trace of the call to New which took the Buffer.
+	CheckedOut time.Time // When the Buffer was taken.
+}
+
+// leakFinalizers records which Buffers have a leak finalizer set, whichever tracker set
+// it, so a finalizer outlives neither its Buffer's checkin nor the tracker being replaced.
+var leakFinalizers = struct {
+	sync.Mutex
+	count atomic.Int64 // Size of addrs, to skip the lock while no finalizers are set.
+	addrs map[uintptr]struct{}
+}{
+	addrs: make(map[uintptr]struct{}),
+}
+
+// setLeakFinalizer sets the leak finalizer of a Buffer, replacing any set before.
+func setLeakFinalizer(buf *bytes.Buffer, finalizer func(*bytes.Buffer)) {
+	addr := uintptr(unsafe.Pointer(buf))
+
+	leakFinalizers.Lock()
+	defer leakFinalizers.Unlock()
+
+	if _, set := leakFinalizers.addrs[addr]; set {
+		runtime.SetFinalizer(buf, nil)
+	} else {
+		leakFinalizers.addrs[addr] = struct{}{}
+		leakFinalizers.count.Add(1)
+	}
+
+	runtime.SetFinalizer(buf, func(buf *bytes.Buffer) {
+		forgetLeakFinalizer(addr)
+		finalizer(buf)
+	})
+}
+
+// forgetLeakFinalizer records that the leak finalizer at addr has run.
+func forgetLeakFinalizer(addr uintptr) {
+	leakFinalizers.Lock()
+	defer leakFinalizers.Unlock()
+
+	if _, set := leakFinalizers.addrs[addr]; set {
+		delete(leakFinalizers.addrs, addr)
+		leakFinalizers.count.Add(-1)
+	}
+}
+
+// clearLeakFinalizer removes the leak finalizer of a Buffer, if it has one. Only
+// Buffers given a finalizer by setLeakFinalizer are touched, as SetFinalizer panics
+// on pointers which aren't to the start of an allocation.
+func clearLeakFinalizer(buf *bytes.Buffer) {
+	if leakFinalizers.count.Load() == 0 {
+		return
+	}
+
+	addr := uintptr(unsafe.Pointer(buf))
+
+	leakFinalizers.Lock()
+	defer leakFinalizers.Unlock()
+
+	if _, set := leakFinalizers.addrs[addr]; set {
+		delete(leakFinalizers.addrs, addr)
+		leakFinalizers.count.Add(-1)
+		runtime.SetFinalizer(buf, nil)
+	}
+}
+
+// leakTracker follows the Buffers checked out of a pool. A nil *leakTracker is
+// valid and tracks nothing, which is how leak detection is disabled.
+type leakTracker struct {
+	report func(BufferLeak)
+
+	mu sync.Mutex
+	// Addresses rather than pointers, so that tracking a Buffer doesn't keep it alive.
+	out map[uintptr]struct{}
+}
+
+// checkout starts tracking a Buffer handed out by New.
+func (t *leakTracker) checkout(buf *bytes.Buffer) {
+	if t == nil {
+		return
+	}
+
+	stack := make([]byte, 4096)
+	stack = stack[:runtime.Stack(stack, false)]
+
+	leak := BufferLeak{
+		Stack:      string(stack),
+		CheckedOut: time.Now(),
+	}
+
+	addr := uintptr(unsafe.Pointer(buf))
+
+	t.mu.Lock()
+	t.out[addr] = struct{}{}
+	t.mu.Unlock()
+
+	setLeakFinalizer(buf, func(*bytes.Buffer) {
+		t.mu.Lock()
+		delete(t.out, addr)
+		t.mu.Unlock()
+
+		t.report(leak)
+	})
+}
+
+// checkin stops tracking a Buffer given to Recycle. Buffers which were not
+// checked out of the pool are ignored. The Buffer's finalizer is removed even
+// if it was set by a tracker since replaced, or if tracking is now disabled.
+func (t *leakTracker) checkin(buf *bytes.Buffer) {
+	clearLeakFinalizer(buf)
+
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	delete(t.out, uintptr(unsafe.Pointer(buf)))
+	t.mu.Unlock()
+}
+
+// outstanding returns the number of tracked Buffers.
+func (t *leakTracker) outstanding() int {
+	if t == nil {
+		return 0
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return len(t.out)
+}
+
+// EnableLeakDetection is a debugging aid which records the stack of every call to New
+// and calls report for each Buffer that is garbage collected without being recycled.
+// Capturing stacks is expensive, so this should not be left on in production. Only
+// Buffers taken after leak detection is enabled are tracked. Passing a nil report
+// disables leak detection.
+func (pool *BufferPool) EnableLeakDetection(report func(BufferLeak)) {
+	if report == nil {
+		pool.leaks.Store(nil)
+		return
+	}
+
+	pool.leaks.Store(&leakTracker{
+		report: report,
+		out:    make(map[uintptr]struct{}),
+	})
+}
+
+// Outstanding returns the number of Buffers taken from the pool which have not
+// yet been recycled. It is only tracked while leak detection is enabled.
+func (pool *BufferPool) Outstanding() int {
+	return pool.leaks.Load().outstanding()
+}
@@ -26,48 +26,447 @@
 
 package util
 
-import "bytes"
+import (
+	"bytes"
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
 
 // BufferPool holds the Buffers in a Channel as a queue.
 type BufferPool struct {
 	Buffers chan *bytes.Buffer
+
+	// MaxCapacity is the largest capacity of a Buffer which will be kept by Recycle.
+	// Larger Buffers are dropped so one huge message doesn't pin its memory for the
+	// life of the pool. Zero means there is no limit.
+	MaxCapacity int
+
+	initialCapacity int // Capacity of Buffers allocated by New.
+	noMetrics       bool
+	zeroOnRecycle   bool // Whether Recycle zeroes the bytes of each Buffer.
+	onGet           func(capacity int)
+	onPut           func(capacity int)
+
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	recycles  atomic.Uint64
+	discards  atomic.Uint64
+	oversized atomic.Uint64
+	trimmed   atomic.Uint64
+
+	leaks  atomic.Pointer[leakTracker]
+	idle   *idleTracker
+	checks atomic.Pointer[recycleChecker]
+
+	lowWater atomic.Int64 // Fewest idle Buffers seen since the last trim.
+
+	mu       sync.Mutex // Guards trimStop and done.
+	trimStop chan struct{}
+	done     chan struct{}                       // Closed by Close, created on first use.
+	release  atomic.Pointer[func(*bytes.Buffer)] // Passed to Close, stored before closed is set.
+	closed   atomic.Bool
+}
+
+// BufferPoolStats is a point in time copy of the counters of a BufferPool.
+type BufferPoolStats struct {
+	Hits      uint64 // Buffers handed out by New from the pool.
+	Misses    uint64 // Buffers allocated by New because the pool was empty.
+	Recycles  uint64 // Buffers returned to the pool by Recycle.
+	Discards  uint64 // Buffers dropped by Recycle because the pool was full.
+	Oversized uint64 // Buffers dropped by Recycle for exceeding MaxCapacity.
+	Trimmed   uint64 // Idle Buffers released by the trimmer or the idle timeout.
 }
 
 // NewBufferPool creates a new object pool of bytes.Buffer.
+// It is the same as NewBufferPoolWith(WithMaxBuffers(max)).
 func NewBufferPool(max int) *BufferPool {
-	return &BufferPool{
-		Buffers: make(chan *bytes.Buffer, max),
+	return NewBufferPoolWith(WithMaxBuffers(max))
+}
+
+// count increments one of the pool's counters, unless metrics are disabled.
+func (pool *BufferPool) count(counter *atomic.Uint64) {
+	if !pool.noMetrics {
+		counter.Add(1)
 	}
 }
 
 // Warmup fills the BufferPool with the specified number of objects
-// up to one below the maximum capacity of the internal channel
+// up to one below the maximum capacity of the internal channel, each
+// pre-allocated to hold length bytes.
 func (pool *BufferPool) Warmup(num, length int) {
 	for i := 0; i < num; i++ {
-		select {
-		case pool.Buffers <- &bytes.Buffer{}: // Add the new buffer to the pool.
-		default: // We're full now because we got blocked trying to add that buffer.
+		if !pool.warmOne(length) {
 			return
 		}
 	}
 }
 
+// WarmupAsync fills the BufferPool like Warmup, but gradually from a background
+// goroutine which adds one Buffer every interval, so a large pool doesn't cause a
+// latency spike at startup. An interval of zero or less adds them without a delay.
+// The returned channel is closed once warmup has finished, which happens early if
+// the pool fills up or is closed.
+func (pool *BufferPool) WarmupAsync(num, length int, interval time.Duration) <-chan struct{} {
+	finished := make(chan struct{})
+	done := pool.doneChan()
+
+	go func() {
+		defer close(finished)
+
+		var tick <-chan time.Time // Left nil without an interval.
+		if interval > 0 {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			tick = ticker.C
+		}
+
+		for i := 0; i < num; i++ {
+			if !pool.warmOne(length) {
+				return
+			}
+
+			if tick == nil {
+				continue
+			}
+
+			select {
+			case <-tick:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return finished
+}
+
+// warmOne adds a new Buffer pre-allocated to hold length bytes to the pool.
+// Returns false if the pool is full or closed.
+func (pool *BufferPool) warmOne(length int) bool {
+	if pool.closed.Load() || len(pool.Buffers) == cap(pool.Buffers) {
+		return false // Don't allocate a buffer with nowhere to put it.
+	}
+
+	buf := &bytes.Buffer{}
+	buf.Grow(length)
+
+	pool.idle.stamp(buf)
+	select {
+	case pool.Buffers <- buf: // Add the new buffer to the pool.
+		// Close may have drained the pool just before this Buffer was added.
+		if pool.closed.Load() {
+			pool.drain(*pool.release.Load())
+			return false
+		}
+		return true
+	default: // We're full now because we got blocked trying to add that buffer.
+		pool.idle.forget(buf)
+		return false
+	}
+}
+
 // New takes a Buffer from the pool.
-func (pool *BufferPool) New() (buf *bytes.Buffer) {
+func (pool *BufferPool) New() *bytes.Buffer {
+	return pool.handOut(pool.take(), 0)
+}
+
+// sizedScanLimit is the most Buffers NewSized looks at for one which is big enough.
+const sizedScanLimit = 4
+
+// NewSized takes a Buffer from the pool which can hold n bytes without reallocating.
+// It prefers a pooled Buffer whose capacity already covers n, looking at a few of
+// them and returning the others to the pool, and otherwise grows the largest seen.
+func (pool *BufferPool) NewSized(n int) *bytes.Buffer {
+	var best *bytes.Buffer
+
+	for i := 0; i < sizedScanLimit && (best == nil || best.Cap() < n); i++ {
+		buf := pool.take()
+		if buf == nil {
+			break
+		}
+
+		if best == nil || buf.Cap() > best.Cap() {
+			buf, best = best, buf
+		}
+		if buf != nil {
+			pool.putBack(buf) // Still stamped with when it was recycled.
+		}
+	}
+
+	return pool.handOut(best, n)
+}
+
+// handOut prepares a Buffer taken from the pool, or a new one if buf is nil,
+// to be handed to the caller with room for at least size bytes.
+func (pool *BufferPool) handOut(buf *bytes.Buffer, size int) *bytes.Buffer {
+	if buf != nil {
+		pool.count(&pool.hits)
+		pool.markLowWater()
+		pool.idle.forget(buf)
+		pool.checks.Load().taken(buf)
+		buf.Grow(size)
+	} else {
+		pool.count(&pool.misses)
+		buf = &bytes.Buffer{}
+		buf.Grow(max(pool.initialCapacity, size))
+	}
+	pool.leaks.Load().checkout(buf)
+	if pool.onGet != nil {
+		pool.onGet(buf.Cap())
+	}
+	return buf
+}
+
+// putBack returns a Buffer taken by the pool itself, dropping it if the pool has
+// filled up in the meantime.
+func (pool *BufferPool) putBack(buf *bytes.Buffer) {
 	select {
-	case buf = <-pool.Buffers:
+	case pool.Buffers <- buf:
 	default:
-		buf = &bytes.Buffer{}
+		pool.idle.forget(buf)
+		pool.checks.Load().dropped(buf)
+		pool.count(&pool.discards)
+	}
+}
+
+// take removes a Buffer from the pool without waiting, evicting any Buffers idle
+// for longer than the idle timeout on the way. Returns nil if the pool is empty.
+// The Buffer keeps its idle stamp, so it can be put back as it was.
+func (pool *BufferPool) take() *bytes.Buffer {
+	for {
+		select {
+		case buf := <-pool.Buffers:
+			if !pool.idle.expired(buf) {
+				return buf
+			}
+			pool.idle.forget(buf)
+			pool.checks.Load().dropped(buf)
+			pool.count(&pool.trimmed)
+		default:
+			return nil
+		}
+	}
+}
+
+// GetContext takes a Buffer from the pool, waiting for one to be recycled if the pool
+// is empty rather than allocating a new one. Together with Warmup, this lets the pool
+// act as a hard limit on the number of Buffers in use; note that Buffers dropped by
+// Recycle for exceeding MaxCapacity are not replaced. Returns the context's error
+// if it is done before a Buffer becomes available, or ErrPoolClosed if the pool is closed.
+func (pool *BufferPool) GetContext(ctx context.Context) (*bytes.Buffer, error) {
+	select {
+	case buf := <-pool.Buffers:
+		pool.count(&pool.hits)
+		pool.markLowWater()
+		pool.idle.forget(buf) // Never evicted here, as that would shrink the limit.
+		pool.checks.Load().taken(buf)
+		pool.leaks.Load().checkout(buf)
+		if pool.onGet != nil {
+			pool.onGet(buf.Cap())
+		}
+		return buf, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-pool.doneChan():
+		return nil, ErrPoolClosed
 	}
-	return
 }
 
 // Recycle returns a BUffer to the pool.
+// Buffers which have grown beyond MaxCapacity are dropped instead.
 func (pool *BufferPool) Recycle(buf *bytes.Buffer) {
+	checks := pool.checks.Load()
+	checks.recycled(buf)
+	pool.leaks.Load().checkin(buf)
+	if pool.onPut != nil {
+		pool.onPut(buf.Cap())
+	}
+
+	if pool.zeroOnRecycle {
+		zeroBuffer(buf)
+	}
+
+	if pool.closed.Load() {
+		checks.dropped(buf)
+		return
+	}
+
+	if pool.MaxCapacity > 0 && buf.Cap() > pool.MaxCapacity {
+		pool.count(&pool.oversized)
+		checks.dropped(buf)
+		return
+	}
+
 	buf.Reset()
+	pool.idle.stamp(buf)
 	select {
 	case pool.Buffers <- buf:
+		pool.count(&pool.recycles)
+		// Close may have drained the pool just before this Buffer was added.
+		if pool.closed.Load() {
+			pool.drain(*pool.release.Load())
+		}
 	default:
+		pool.count(&pool.discards)
+		pool.idle.forget(buf)
+		checks.dropped(buf)
 		// let it go, let it go...
 	}
 }
+
+// WithBuffer takes a Buffer from the pool, passes it to fn and recycles it once fn
+// returns, even if fn panics. fn must not keep the Buffer or its bytes after it
+// returns. Returns the error returned by fn.
+func (pool *BufferPool) WithBuffer(fn func(*bytes.Buffer) error) error {
+	buf := pool.New()
+	defer pool.Recycle(buf)
+
+	return fn(buf)
+}
+
+// zeroBuffer resets a Buffer and overwrites its entire underlying array with zeroes,
+// including bytes already read or truncated away.
+func zeroBuffer(buf *bytes.Buffer) {
+	buf.Reset()
+	b := buf.AvailableBuffer()
+	clear(b[:cap(b)])
+}
+
+// Stats returns the current counters of the pool, which show how well its size
+// suits the workload: Misses count allocations the pool failed to save, and
+// Discards count the extra Buffers from bursts that it had no room to keep.
+func (pool *BufferPool) Stats() BufferPoolStats {
+	return BufferPoolStats{
+		Hits:      pool.hits.Load(),
+		Misses:    pool.misses.Load(),
+		Recycles:  pool.recycles.Load(),
+		Discards:  pool.discards.Load(),
+		Oversized: pool.oversized.Load(),
+		Trimmed:   pool.trimmed.Load(),
+	}
+}
+
+// markLowWater records the number of idle Buffers left after one was taken,
+// if it is the fewest seen since the last trim.
+func (pool *BufferPool) markLowWater() {
+	idle := int64(len(pool.Buffers))
+	for {
+		low := pool.lowWater.Load()
+		if idle >= low || pool.lowWater.CompareAndSwap(low, idle) {
+			return
+		}
+	}
+}
+
+// StartTrimmer starts a background goroutine which, once every idle period, releases
+// the Buffers that sat in the pool for the whole period without being needed, so the
+// memory retained after a burst of traffic is eventually returned to the GC. Starting
+// a trimmer replaces any trimmer already running. An idle period of zero or less
+// disables trimming, stopping any trimmer already running.
+func (pool *BufferPool) StartTrimmer(idle time.Duration) {
+	if idle <= 0 {
+		pool.StopTrimmer()
+		return
+	}
+
+	stop := make(chan struct{})
+
+	pool.mu.Lock()
+	if pool.trimStop != nil {
+		close(pool.trimStop)
+	}
+	pool.trimStop = stop
+	pool.mu.Unlock()
+
+	pool.lowWater.Store(int64(len(pool.Buffers)))
+
+	go func() {
+		ticker := time.NewTicker(idle)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				pool.trim()
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// StopTrimmer stops the background trimmer, if one is running.
+func (pool *BufferPool) StopTrimmer() {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	if pool.trimStop != nil {
+		close(pool.trimStop)
+		pool.trimStop = nil
+	}
+}
+
+// trim releases as many Buffers as stayed idle for the whole of the last period.
+func (pool *BufferPool) trim() {
+drain:
+	for unused := pool.lowWater.Load(); unused > 0; unused-- {
+		select {
+		case buf := <-pool.Buffers:
+			pool.count(&pool.trimmed)
+			pool.idle.forget(buf)
+			pool.checks.Load().dropped(buf)
+		default: // Emptied by callers in the meantime.
+			break drain
+		}
+	}
+
+	pool.lowWater.Store(int64(len(pool.Buffers)))
+}
+
+// doneChan returns the channel which is closed when the pool is closed.
+func (pool *BufferPool) doneChan() chan struct{} {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	if pool.done == nil {
+		pool.done = make(chan struct{})
+	}
+	return pool.done
+}
+
+// drain removes all of the Buffers from the pool, passing each to release if not nil.
+func (pool *BufferPool) drain(release func(*bytes.Buffer)) {
+	for {
+		select {
+		case buf := <-pool.Buffers:
+			pool.idle.forget(buf)
+			pool.checks.Load().dropped(buf)
+			if release != nil {
+				release(buf)
+			}
+		default:
+			return
+		}
+	}
+}
+
+// Close shuts the pool down: the trimmer is stopped, every Buffer waiting in the pool
+// is removed and passed to release (if not nil), and later calls to Recycle do nothing.
+// New keeps working after Close but always allocates, while GetContext returns
+// ErrPoolClosed. It is safe to call more than once. A Buffer recycled while Close
+// runs may be passed to release from Recycle instead.
+func (pool *BufferPool) Close(release func(*bytes.Buffer)) {
+	if !pool.release.CompareAndSwap(nil, &release) {
+		return
+	}
+	pool.closed.Store(true)
+
+	pool.StopTrimmer()
+
+	done := pool.doneChan()
+	close(done)
+
+	pool.drain(release)
+}
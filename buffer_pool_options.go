@@ -0,0 +1,145 @@
+/*
+   Copyright (c) 2020, btnmasher
+   All rights reserved.
+
+   Redistribution and use in source and binary forms, with or without modification, are permitted provided that
+   the following conditions are met:
+
+   1. Redistributions of source code must retain the above copyright notice, this list of conditions and the
+      following disclaimer.
+
+   2. Redistributions in binary form must reproduce the above copyright notice, this list of conditions and
+      the following disclaimer in the documentation and/or other materials provided with the distribution.
+
+   3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse or
+      promote products derived from this software without specific prior written permission.
+
+   THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED
+   WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+   PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR
+   ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+   TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+   HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+   NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+   POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package util
+
+import (
+	"bytes"
+	"time"
+)
+
+// DefaultMaxBuffers is the number of Buffers held by a pool created
+// by NewBufferPoolWith without WithMaxBuffers.
+const DefaultMaxBuffers = 64
+
+// bufferPoolConfig collects the settings applied by BufferPoolOptions.
+type bufferPoolConfig struct {
+	maxBuffers      int
+	initialCapacity int
+	maxRetained     int
+	metrics         bool
+	zeroOnRecycle   bool
+	idleTimeout     time.Duration
+	onGet           func(int)
+	onPut           func(int)
+}
+
+// BufferPoolOption configures a BufferPool created by NewBufferPoolWith.
+type BufferPoolOption func(*bufferPoolConfig)
+
+// WithMaxBuffers sets the number of Buffers the pool holds.
+func WithMaxBuffers(max int) BufferPoolOption {
+	return func(c *bufferPoolConfig) {
+		c.maxBuffers = max
+	}
+}
+
+// WithInitialCapacity pre-allocates each Buffer the pool creates to hold
+// size bytes, so first use doesn't reallocate.
+func WithInitialCapacity(size int) BufferPoolOption {
+	return func(c *bufferPoolConfig) {
+		c.initialCapacity = size
+	}
+}
+
+// WithMaxRetainedCapacity sets the pool's MaxCapacity, the largest capacity
+// of a Buffer which will be kept by Recycle.
+func WithMaxRetainedCapacity(size int) BufferPoolOption {
+	return func(c *bufferPoolConfig) {
+		c.maxRetained = size
+	}
+}
+
+// WithMetrics turns the counters reported by Stats on or off. They are on by
+// default; turning them off saves a few atomic operations on each call.
+func WithMetrics(enabled bool) BufferPoolOption {
+	return func(c *bufferPoolConfig) {
+		c.metrics = enabled
+	}
+}
+
+// WithZeroOnRecycle makes Recycle overwrite the whole of each Buffer's underlying
+// array with zeroes, rather than just resetting it, so secrets handled by one user
+// can't bleed into the next. This costs a pass over the Buffer's capacity.
+func WithZeroOnRecycle() BufferPoolOption {
+	return func(c *bufferPoolConfig) {
+		c.zeroOnRecycle = true
+	}
+}
+
+// WithIdleTimeout makes the pool release Buffers which have waited in it for longer
+// than timeout, so the memory it holds follows the actual load. Recycle and Warmup
+// stamp each Buffer with the time it went into the pool, and only New and NewSized
+// evict the stale ones, as they take them out, without a background goroutine; see
+// StartTrimmer for a pool which may sit unused. The stamps cost a mutex on every
+// call to New and Recycle. Evicted Buffers are not replaced, so this doesn't mix
+// with using GetContext as a hard limit.
+func WithIdleTimeout(timeout time.Duration) BufferPoolOption {
+	return func(c *bufferPoolConfig) {
+		c.idleTimeout = timeout
+	}
+}
+
+// WithOnGet sets a function called with the capacity of every Buffer handed out by
+// New or GetContext, so applications can plug in their own tracing or metrics.
+// It is called on the caller's goroutine and should be quick.
+func WithOnGet(hook func(capacity int)) BufferPoolOption {
+	return func(c *bufferPoolConfig) {
+		c.onGet = hook
+	}
+}
+
+// WithOnPut sets a function called with the capacity of every Buffer given to
+// Recycle, whether or not the pool keeps it. It is called on the caller's
+// goroutine and should be quick.
+func WithOnPut(hook func(capacity int)) BufferPoolOption {
+	return func(c *bufferPoolConfig) {
+		c.onPut = hook
+	}
+}
+
+// NewBufferPoolWith creates a new object pool of bytes.Buffer configured by the given options.
+func NewBufferPoolWith(opts ...BufferPoolOption) *BufferPool {
+	config := bufferPoolConfig{
+		maxBuffers: DefaultMaxBuffers,
+		metrics:    true,
+	}
+
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	return &BufferPool{
+		Buffers:         make(chan *bytes.Buffer, max(config.maxBuffers, 0)),
+		MaxCapacity:     config.maxRetained,
+		initialCapacity: config.initialCapacity,
+		noMetrics:       !config.metrics,
+		zeroOnRecycle:   config.zeroOnRecycle,
+		idle:            newIdleTracker(config.idleTimeout),
+		onGet:           config.onGet,
+		onPut:           config.onPut,
+	}
+}
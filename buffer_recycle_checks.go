@@ -0,0 +1,128 @@
+/*
+   Copyright (c) 2020, btnmasher
+   All rights reserved.
+
+   Redistribution and use in source and binary forms, with or without modification, are permitted provided that
+   the following conditions are met:
+
+   1. Redistributions of source code must retain the above copyright notice, this list of conditions and the
+      following disclaimer.
+
+   2. Redistributions in binary form must reproduce the above copyright notice, this list of conditions and
+      the following disclaimer in the documentation and/or other materials provided with the distribution.
+
+   3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse or
+      promote products derived from this software without specific prior written permission.
+
+   THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED
+   WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+   PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR
+   ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+   TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+   HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+   NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+   POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package util
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"sync"
+	"unsafe"
+)
+
+// recycleChecker follows the Buffers sitting in a pool, to catch a Buffer being
+// recycled twice or written to after it was recycled. A nil *recycleChecker is
+// valid and checks nothing, which is how recycle checks are disabled.
+type recycleChecker struct {
+	mu   sync.Mutex
+	idle map[*bytes.Buffer]recycleRecord
+	// Generation of each Buffer by address, so that it survives a trip out of the pool
+	// without keeping the Buffer alive.
+	generations map[uintptr]uint64
+}
+
+// recycleRecord describes the call to Recycle which put a Buffer in the pool.
+type recycleRecord struct {
+	generation uint64
+	stack      string
+}
+
+// recycled records that a Buffer has been given to Recycle, panicking if it
+// is already waiting in the pool.
+func (c *recycleChecker) recycled(buf *bytes.Buffer) {
+	if c == nil {
+		return
+	}
+
+	stack := make([]byte, 4096)
+	stack = stack[:runtime.Stack(stack, false)]
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if prev, ok := c.idle[buf]; ok {
+		panic(fmt.Sprintf("util: Buffer recycled twice (generation %d)\n\nfirst Recycle:\n%s\nsecond Recycle:\n%s",
+			prev.generation, prev.stack, stack))
+	}
+
+	addr := uintptr(unsafe.Pointer(buf))
+	c.generations[addr]++
+	c.idle[buf] = recycleRecord{generation: c.generations[addr], stack: string(stack)}
+}
+
+// taken records that a Buffer has left the pool, panicking if it was written
+// to while it sat there.
+func (c *recycleChecker) taken(buf *bytes.Buffer) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	prev, ok := c.idle[buf]
+	if !ok {
+		return
+	}
+	delete(c.idle, buf)
+
+	if buf.Len() != 0 {
+		panic(fmt.Sprintf("util: Buffer used after Recycle (generation %d)\n\nRecycle:\n%s",
+			prev.generation, prev.stack))
+	}
+}
+
+// dropped forgets a Buffer which the pool let go of, so it can be collected.
+func (c *recycleChecker) dropped(buf *bytes.Buffer) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	delete(c.idle, buf)
+	delete(c.generations, uintptr(unsafe.Pointer(buf)))
+	c.mu.Unlock()
+}
+
+// EnableRecycleChecks is a debugging aid which panics, with the stacks of the calls
+// involved, when a Buffer is given to Recycle while it is already in the pool, or when
+// a Buffer taken from the pool turns out to have been written to after it was recycled.
+// Each Buffer carries a generation, bumped every time it is recycled, which is included
+// in the panic to tell its trips through the pool apart. Capturing stacks is expensive,
+// so this should not be left on in production. Only Buffers recycled after the checks
+// are enabled are tracked, and a Buffer the pool drops is forgotten.
+func (pool *BufferPool) EnableRecycleChecks(enabled bool) {
+	if !enabled {
+		pool.checks.Store(nil)
+		return
+	}
+
+	pool.checks.Store(&recycleChecker{
+		idle:        make(map[*bytes.Buffer]recycleRecord),
+		generations: make(map[uintptr]uint64),
+	})
+}
@@ -0,0 +1,110 @@
+/*
+   Copyright (c) 2020, btnmasher
+   All rights reserved.
+
+   Redistribution and use in source and binary forms, with or without modification, are permitted provided that
+   the following conditions are met:
+
+   1. Redistributions of source code must retain the above copyright notice, this list of conditions and the
+      following disclaimer.
+
+   2. Redistributions in binary form must reproduce the above copyright notice, this list of conditions and
+      the following disclaimer in the documentation and/or other materials provided with the distribution.
+
+   3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse or
+      promote products derived from this software without specific prior written permission.
+
+   THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED
+   WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+   PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR
+   ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+   TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+   HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+   NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+   POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package util
+
+import (
+	"bufio"
+	"io"
+)
+
+// ReaderPool is a pool of bufio.Readers of a fixed buffer size.
+type ReaderPool struct {
+	pool *Pool[*bufio.Reader]
+}
+
+// NewReaderPool creates a new pool holding at most max bufio.Readers,
+// each with a buffer of the given size.
+func NewReaderPool(max, size int) *ReaderPool {
+	return &ReaderPool{
+		pool: NewPool(max,
+			func() *bufio.Reader { return bufio.NewReaderSize(nil, size) },
+			func(br *bufio.Reader) { br.Reset(nil) },
+		),
+	}
+}
+
+// Warmup fills the pool with the specified number of bufio.Readers.
+func (pool *ReaderPool) Warmup(num int) {
+	pool.pool.Warmup(num)
+}
+
+// New takes a bufio.Reader from the pool, reading from r.
+func (pool *ReaderPool) New(r io.Reader) *bufio.Reader {
+	br := pool.pool.New()
+	br.Reset(r)
+	return br
+}
+
+// Recycle returns a bufio.Reader to the pool, discarding any buffered data
+// and dropping its reference to the underlying reader.
+func (pool *ReaderPool) Recycle(br *bufio.Reader) {
+	pool.pool.Recycle(br)
+}
+
+// Close empties the pool and makes later calls to Recycle do nothing.
+func (pool *ReaderPool) Close() {
+	pool.pool.Close(nil)
+}
+
+// WriterPool is a pool of bufio.Writers of a fixed buffer size.
+type WriterPool struct {
+	pool *Pool[*bufio.Writer]
+}
+
+// NewWriterPool creates a new pool holding at most max bufio.Writers,
+// each with a buffer of the given size.
+func NewWriterPool(max, size int) *WriterPool {
+	return &WriterPool{
+		pool: NewPool(max,
+			func() *bufio.Writer { return bufio.NewWriterSize(nil, size) },
+			func(bw *bufio.Writer) { bw.Reset(nil) },
+		),
+	}
+}
+
+// Warmup fills the pool with the specified number of bufio.Writers.
+func (pool *WriterPool) Warmup(num int) {
+	pool.pool.Warmup(num)
+}
+
+// New takes a bufio.Writer from the pool, writing to w.
+func (pool *WriterPool) New(w io.Writer) *bufio.Writer {
+	bw := pool.pool.New()
+	bw.Reset(w)
+	return bw
+}
+
+// Recycle returns a bufio.Writer to the pool. Any data which has not been
+// flushed is discarded, so callers should Flush before recycling.
+func (pool *WriterPool) Recycle(bw *bufio.Writer) {
+	pool.pool.Recycle(bw)
+}
+
+// Close empties the pool and makes later calls to Recycle do nothing.
+func (pool *WriterPool) Close() {
+	pool.pool.Close(nil)
+}
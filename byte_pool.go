@@ -0,0 +1,111 @@
+/*
+   Copyright (c) 2020, btnmasher
+   All rights reserved.
+
+   Redistribution and use in source and binary forms, with or without modification, are permitted provided that
+   the following conditions are met:
+
+   1. Redistributions of source code must retain the above copyright notice, this list of conditions and the
+      following disclaimer.
+
+   2. Redistributions in binary form must reproduce the above copyright notice, this list of conditions and
+      the following disclaimer in the documentation and/or other materials provided with the distribution.
+
+   3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse or
+      promote products derived from this software without specific prior written permission.
+
+   THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED
+   WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+   PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR
+   ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+   TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+   HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+   NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+   POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package util
+
+import (
+	"math/bits"
+	"sync"
+)
+
+// BytePool hands out []byte slices from pools bucketed by power-of-two size classes,
+// so a request for n bytes is served by a slice from the smallest class that fits it.
+// Requests larger than the biggest class are allocated directly and not pooled.
+type BytePool struct {
+	classes []sync.Pool
+	minBits int
+}
+
+// NewBytePool creates a new BytePool whose size classes are the powers of two from
+// minSize up to maxSize, each rounded up to a power of two.
+func NewBytePool(minSize, maxSize int) *BytePool {
+	if minSize < 1 {
+		minSize = 1
+	}
+	if maxSize < minSize {
+		maxSize = minSize
+	}
+
+	minBits := bits.Len(uint(powerOfTwo(minSize) - 1))
+	maxBits := bits.Len(uint(powerOfTwo(maxSize) - 1))
+
+	pool := &BytePool{
+		classes: make([]sync.Pool, maxBits-minBits+1),
+		minBits: minBits,
+	}
+
+	for i := range pool.classes {
+		size := 1 << (minBits + i)
+		pool.classes[i].New = func() any {
+			b := make([]byte, size)
+			return &b
+		}
+	}
+
+	return pool
+}
+
+// class returns the index of the smallest size class holding n bytes,
+// or -1 if n is larger than the biggest class.
+func (pool *BytePool) class(n int) int {
+	idx := 0
+	if n > 1 {
+		idx = bits.Len(uint(n-1)) - pool.minBits
+	}
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(pool.classes) {
+		return -1
+	}
+	return idx
+}
+
+// Get returns a slice of length n. Its capacity is that of the size class
+// it came from, which may be larger than n.
+func (pool *BytePool) Get(n int) []byte {
+	idx := pool.class(n)
+	if idx < 0 {
+		return make([]byte, n)
+	}
+
+	b := pool.classes[idx].Get().(*[]byte)
+	return (*b)[:n]
+}
+
+// Put returns a slice obtained from Get to the pool. Slices whose capacity is not
+// exactly one of the size classes, including oversized ones allocated by Get, are
+// dropped. The slice must not be used after it has been put back.
+func (pool *BytePool) Put(b []byte) {
+	size := cap(b)
+	idx := pool.class(size)
+	if idx < 0 || size != 1<<(pool.minBits+idx) {
+		return
+	}
+
+	b = b[:size]
+	pool.classes[idx].Put(&b)
+}
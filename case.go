@@ -0,0 +1,126 @@
+/*
+   Copyright (c) 2020, btnmasher
+   All rights reserved.
+
+   Redistribution and use in source and binary forms, with or without modification, are permitted provided that
+   the following conditions are met:
+
+   1. Redistributions of source code must retain the above copyright notice, this list of conditions and the
+      following disclaimer.
+
+   2. Redistributions in binary form must reproduce the above copyright notice, this list of conditions and
+      the following disclaimer in the documentation and/or other materials provided with the distribution.
+
+   3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse or
+      promote products derived from this software without specific prior written permission.
+
+   THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED
+   WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+   PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR
+   ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+   TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+   HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+   NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+   POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package util
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// SplitWords splits an identifier or phrase into its words, whatever naming convention
+// it uses. Words are separated by anything other than letters and digits, and by case
+// changes: a lower case letter followed by an upper case one, or the last letter of an
+// acronym followed by a capitalized word, so "HTTPServer" is "HTTP" and "Server".
+// Digits stay with the word they follow, so "utf8Reader" is "utf8" and "Reader".
+func SplitWords(s string) []string {
+	var words []string
+	runes := []rune(s)
+	start := -1 // Start of the current word, or -1 between words.
+
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			if start >= 0 {
+				words = append(words, string(runes[start:i]))
+				start = -1
+			}
+			continue
+		}
+
+		if start < 0 {
+			start = i
+			continue
+		}
+
+		prev := runes[i-1]
+		if unicode.IsUpper(r) {
+			// A lower case letter or digit followed by an upper case letter starts a word.
+			boundary := unicode.IsLower(prev) || unicode.IsDigit(prev)
+			// So does the last upper case letter of an acronym, when a lower case letter follows it.
+			boundary = boundary || unicode.IsUpper(prev) && i+1 < len(runes) && unicode.IsLower(runes[i+1])
+
+			if boundary {
+				words = append(words, string(runes[start:i]))
+				start = i
+			}
+		}
+	}
+
+	if start >= 0 {
+		words = append(words, string(runes[start:]))
+	}
+
+	return words
+}
+
+// ToSnakeCase converts s to snake_case, so "HTTPServer" becomes "http_server".
+func ToSnakeCase(s string) string {
+	return joinLower(SplitWords(s), "_")
+}
+
+// ToScreamingSnakeCase converts s to SCREAMING_SNAKE_CASE, so "HTTPServer" becomes "HTTP_SERVER".
+func ToScreamingSnakeCase(s string) string {
+	return strings.ToUpper(ToSnakeCase(s))
+}
+
+// ToKebabCase converts s to kebab-case, so "HTTPServer" becomes "http-server".
+func ToKebabCase(s string) string {
+	return joinLower(SplitWords(s), "-")
+}
+
+// ToCamelCase converts s to camelCase, so "http_server" becomes "httpServer".
+func ToCamelCase(s string) string {
+	words := SplitWords(s)
+	if len(words) == 0 {
+		return ""
+	}
+	return strings.ToLower(words[0]) + joinCapitalized(words[1:])
+}
+
+// ToPascalCase converts s to PascalCase, so "http_server" becomes "HttpServer".
+func ToPascalCase(s string) string {
+	return joinCapitalized(SplitWords(s))
+}
+
+// joinLower joins the words in lower case, separated by sep.
+func joinLower(words []string, sep string) string {
+	for i, word := range words {
+		words[i] = strings.ToLower(word)
+	}
+	return strings.Join(words, sep)
+}
+
+// joinCapitalized joins the words with their first letter in upper case and the rest in lower case.
+func joinCapitalized(words []string) string {
+	var out strings.Builder
+	for _, word := range words {
+		r, size := utf8.DecodeRuneInString(word)
+		out.WriteRune(unicode.ToUpper(r))
+		out.WriteString(strings.ToLower(word[size:]))
+	}
+	return out.String()
+}
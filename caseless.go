@@ -0,0 +1,239 @@
+/*
+   Copyright (c) 2020, btnmasher
+   All rights reserved.
+
+   Redistribution and use in source and binary forms, with or without modification, are permitted provided that
+   the following conditions are met:
+
+   1. Redistributions of source code must retain the above copyright notice, this list of conditions and the
+      following disclaimer.
+
+   2. Redistributions in binary form must reproduce the above copyright notice, this list of conditions and
+      the following disclaimer in the documentation and/or other materials provided with the distribution.
+
+   3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse or
+      promote products derived from this software without specific prior written permission.
+
+   THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED
+   WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+   PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR
+   ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+   TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+   HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+   NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+   POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package util
+
+import (
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// CaselessKey returns s with every rune replaced by a single representative of its
+// Unicode case folding orbit, in lower case, so two strings have the same key exactly
+// when strings.EqualFold reports them equal. Unlike strings.ToLower, it maps the
+// Kelvin sign to "k", and "ſ" and "ς" to "s" and "σ".
+func CaselessKey(s string) string {
+	var out strings.Builder
+	out.Grow(len(s))
+
+	for _, r := range s {
+		out.WriteRune(unicode.ToLower(foldRep(r)))
+	}
+
+	return out.String()
+}
+
+// foldRep returns the smallest rune in the case folding orbit of r.
+func foldRep(r rune) rune {
+	rep := r
+	for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+		rep = min(rep, f)
+	}
+	return rep
+}
+
+// equalFoldRune reports whether a and b are equal under Unicode case folding.
+func equalFoldRune(a, b rune) bool {
+	if a == b {
+		return true
+	}
+	for f := unicode.SimpleFold(a); f != a; f = unicode.SimpleFold(f) {
+		if f == b {
+			return true
+		}
+	}
+	return false
+}
+
+// foldPrefixLen reports whether s starts with prefix under Unicode case folding,
+// and if so how many bytes of s the prefix matched.
+func foldPrefixLen(s, prefix string) (int, bool) {
+	i := 0
+	for _, p := range prefix {
+		if i == len(s) {
+			return 0, false
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if !equalFoldRune(r, p) {
+			return 0, false
+		}
+		i += size
+	}
+	return i, true
+}
+
+// CaselessHasPrefix reports whether s starts with prefix, ignoring case.
+func CaselessHasPrefix(s, prefix string) bool {
+	_, ok := foldPrefixLen(s, prefix)
+	return ok
+}
+
+// CaselessHasSuffix reports whether s ends with suffix, ignoring case.
+func CaselessHasSuffix(s, suffix string) bool {
+	for suffix != "" {
+		if s == "" {
+			return false
+		}
+		r, size := utf8.DecodeLastRuneInString(s)
+		p, psize := utf8.DecodeLastRuneInString(suffix)
+		if !equalFoldRune(r, p) {
+			return false
+		}
+		s, suffix = s[:len(s)-size], suffix[:len(suffix)-psize]
+	}
+	return true
+}
+
+// CaselessIndex returns the byte index of the first instance of substr in s,
+// ignoring case, or -1 if substr is not in s.
+func CaselessIndex(s, substr string) int {
+	for i := range s {
+		if _, ok := foldPrefixLen(s[i:], substr); ok {
+			return i
+		}
+	}
+	if substr == "" {
+		return len(s) // Only reached when s is empty.
+	}
+	return -1
+}
+
+// CaselessContains reports whether substr is within s, ignoring case.
+func CaselessContains(s, substr string) bool {
+	return CaselessIndex(s, substr) >= 0
+}
+
+// CaselessSet is a set of strings which ignores case, wrapped with a concurrent-safe
+// API. It keeps the spelling each string was first added with.
+type CaselessSet struct {
+	data map[string]string // Original spelling by CaselessKey.
+	sync.RWMutex
+}
+
+// NewCaselessSet initializes and returns a pointer to a new CaselessSet
+// instance holding the given items.
+func NewCaselessSet(items ...string) *CaselessSet {
+	s := &CaselessSet{
+		data: make(map[string]string, len(items)),
+	}
+
+	for _, item := range items {
+		key := CaselessKey(item)
+		if _, exists := s.data[key]; !exists {
+			s.data[key] = item
+		}
+	}
+
+	return s
+}
+
+// Add is used to add an item to the set.
+// Returns true if no item equal to it, ignoring case, was already in the set.
+func (s *CaselessSet) Add(item string) bool {
+	key := CaselessKey(item)
+
+	s.Lock()
+	defer s.Unlock()
+
+	if _, exists := s.data[key]; exists {
+		return false
+	}
+
+	s.data[key] = item
+	return true
+}
+
+// Remove is used to remove the item equal to the given one, ignoring case, from the set.
+// Returns true if there was such an item in the set.
+func (s *CaselessSet) Remove(item string) bool {
+	key := CaselessKey(item)
+
+	s.Lock()
+	defer s.Unlock()
+
+	if _, exists := s.data[key]; !exists {
+		return false
+	}
+
+	delete(s.data, key)
+	return true
+}
+
+// Contains is used to check if an item equal to the given one, ignoring case, is in the set.
+func (s *CaselessSet) Contains(item string) bool {
+	key := CaselessKey(item)
+
+	s.RLock()
+	defer s.RUnlock()
+
+	_, exists := s.data[key]
+	return exists
+}
+
+// Get returns the spelling the item equal to the given one, ignoring case, was added with.
+// Returns false if there is no such item in the set.
+func (s *CaselessSet) Get(item string) (string, bool) {
+	key := CaselessKey(item)
+
+	s.RLock()
+	defer s.RUnlock()
+
+	original, exists := s.data[key]
+	return original, exists
+}
+
+// Len returns the number of items in the set.
+func (s *CaselessSet) Len() int {
+	s.RLock()
+	defer s.RUnlock()
+
+	return len(s.data)
+}
+
+// ForEach will call the provided function for each item in the set.
+func (s *CaselessSet) ForEach(do func(string)) {
+	s.RLock()
+	defer s.RUnlock()
+
+	for _, item := range s.data {
+		do(item)
+	}
+}
+
+// ToSlice returns the items of the set in no particular order.
+func (s *CaselessSet) ToSlice() []string {
+	s.RLock()
+	defer s.RUnlock()
+
+	items := make([]string, 0, len(s.data))
+	for _, item := range s.data {
+		items = append(items, item)
+	}
+
+	return items
+}
@@ -0,0 +1,96 @@
+/*
+   Copyright (c) 2020, btnmasher
+   All rights reserved.
+
+   Redistribution and use in source and binary forms, with or without modification, are permitted provided that
+   the following conditions are met:
+
+   1. Redistributions of source code must retain the above copyright notice, this list of conditions and the
+      following disclaimer.
+
+   2. Redistributions in binary form must reproduce the above copyright notice, this list of conditions and
+      the following disclaimer in the documentation and/or other materials provided with the distribution.
+
+   3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse or
+      promote products derived from this software without specific prior written permission.
+
+   THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED
+   WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+   PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR
+   ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+   TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+   HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+   NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+   POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package util
+
+// OversizePolicy decides what ChunkJoinStringsWith does with an item which is
+// longer than maxlength on its own.
+type OversizePolicy int
+
+const (
+	// OversizeSplit splits the item across strings at rune boundaries. This is the default.
+	OversizeSplit OversizePolicy = iota
+	// OversizeAlone puts the item in a string of its own, breaching the maxlength.
+	OversizeAlone
+	// OversizeError makes ChunkJoinStringsWith return an error wrapping ErrItemTooLong.
+	OversizeError
+)
+
+// chunkConfig collects the settings applied by ChunkOptions.
+type chunkConfig struct {
+	measure      textMeasure
+	oversize     OversizePolicy
+	prefix       string
+	continuation string
+}
+
+// ChunkOption configures how ChunkJoinStringsWith builds its strings.
+type ChunkOption func(*chunkConfig)
+
+// WithOversize sets the policy for items longer than maxlength on their own.
+func WithOversize(policy OversizePolicy) ChunkOption {
+	return func(c *chunkConfig) {
+		c.oversize = policy
+	}
+}
+
+// WithRuneCount measures the maxlength in runes rather than bytes.
+func WithRuneCount() ChunkOption {
+	return func(c *chunkConfig) {
+		c.measure = runeMeasure
+	}
+}
+
+// WithChunkPrefix prepends prefix to every string, such as the command and target
+// of a protocol message. The prefix counts towards the maxlength.
+func WithChunkPrefix(prefix string) ChunkOption {
+	return func(c *chunkConfig) {
+		c.prefix = prefix
+	}
+}
+
+// WithContinuation appends marker, such as " …", to every string but the last, to
+// show that more follows. Room for the marker is kept in every string, so the strings
+// stay within the maxlength whichever of them turns out to be the last.
+func WithContinuation(marker string) ChunkOption {
+	return func(c *chunkConfig) {
+		c.continuation = marker
+	}
+}
+
+// ChunkJoinStringsWith joins the items like ChunkJoinStrings, configured by the
+// given options. Returns an error only under OversizeError.
+func ChunkJoinStringsWith(params []string, maxlength int, sep string, opts ...ChunkOption) ([]string, error) {
+	config := chunkConfig{
+		measure: byteMeasure,
+	}
+
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	return chunkJoin(params, maxlength, sep, config)
+}
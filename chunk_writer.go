@@ -0,0 +1,112 @@
+/*
+   Copyright (c) 2020, btnmasher
+   All rights reserved.
+
+   Redistribution and use in source and binary forms, with or without modification, are permitted provided that
+   the following conditions are met:
+
+   1. Redistributions of source code must retain the above copyright notice, this list of conditions and the
+      following disclaimer.
+
+   2. Redistributions in binary form must reproduce the above copyright notice, this list of conditions and
+      the following disclaimer in the documentation and/or other materials provided with the distribution.
+
+   3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse or
+      promote products derived from this software without specific prior written permission.
+
+   THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED
+   WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+   PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR
+   ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+   TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+   HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+   NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+   POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package util
+
+import "io"
+
+// ChunkWriter is an io.Writer which accumulates what is written to it and passes it on
+// in chunks of exactly size bytes, so a stream can be chunked as it is produced. The
+// last, partial chunk is only passed on by Flush. Like bufio.Writer, a ChunkWriter
+// is not safe for concurrent use.
+type ChunkWriter struct {
+	size int
+	emit func(chunk []byte) error
+	buf  []byte
+}
+
+var _ io.Writer = (*ChunkWriter)(nil)
+
+// NewChunkWriter creates a new ChunkWriter passing each chunk of size bytes to emit.
+// The chunk is only valid until emit returns, so emit must copy it to keep it.
+func NewChunkWriter(size int, emit func(chunk []byte) error) *ChunkWriter {
+	size = max(size, 1)
+
+	return &ChunkWriter{
+		size: size,
+		emit: emit,
+		buf:  make([]byte, 0, size),
+	}
+}
+
+// NewChunkWriterTo creates a new ChunkWriter writing each chunk of size bytes
+// to w with a single call to Write.
+func NewChunkWriterTo(w io.Writer, size int) *ChunkWriter {
+	return NewChunkWriter(size, func(chunk []byte) error {
+		_, err := w.Write(chunk)
+		return err
+	})
+}
+
+// Write adds p to the stream, passing on every chunk it completes. Returns the number
+// of bytes taken from p and the error from passing on a chunk, if any. The bytes of a
+// chunk which failed are dropped.
+func (cw *ChunkWriter) Write(p []byte) (int, error) {
+	written := 0
+
+	for len(p) > 0 {
+		// Pass whole chunks straight through rather than copying them.
+		if len(cw.buf) == 0 && len(p) >= cw.size {
+			if err := cw.emit(p[:cw.size]); err != nil {
+				return written, err
+			}
+			p = p[cw.size:]
+			written += cw.size
+			continue
+		}
+
+		n := min(cw.size-len(cw.buf), len(p))
+		cw.buf = append(cw.buf, p[:n]...)
+		p = p[n:]
+		written += n
+
+		if len(cw.buf) == cw.size {
+			err := cw.emit(cw.buf)
+			cw.buf = cw.buf[:0]
+			if err != nil {
+				return written, err
+			}
+		}
+	}
+
+	return written, nil
+}
+
+// Buffered returns the number of bytes waiting for their chunk to be completed.
+func (cw *ChunkWriter) Buffered() int {
+	return len(cw.buf)
+}
+
+// Flush passes on the bytes waiting in a partial chunk, if there are any.
+func (cw *ChunkWriter) Flush() error {
+	if len(cw.buf) == 0 {
+		return nil
+	}
+
+	err := cw.emit(cw.buf)
+	cw.buf = cw.buf[:0]
+	return err
+}
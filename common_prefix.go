@@ -0,0 +1,93 @@
+/*
+   Copyright (c) 2020, btnmasher
+   All rights reserved.
+
+   Redistribution and use in source and binary forms, with or without modification, are permitted provided that
+   the following conditions are met:
+
+   1. Redistributions of source code must retain the above copyright notice, this list of conditions and the
+      following disclaimer.
+
+   2. Redistributions in binary form must reproduce the above copyright notice, this list of conditions and
+      the following disclaimer in the documentation and/or other materials provided with the distribution.
+
+   3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse or
+      promote products derived from this software without specific prior written permission.
+
+   THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED
+   WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+   PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR
+   ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+   TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+   HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+   NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+   POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package util
+
+import "unicode/utf8"
+
+// LongestCommonPrefix returns the longest string which every string in list starts with.
+// The prefix never ends partway through a multi-byte character.
+func LongestCommonPrefix(list []string) string {
+	if len(list) == 0 {
+		return ""
+	}
+
+	prefix := list[0]
+	for _, s := range list[1:] {
+		n := 0
+		for n < len(prefix) && n < len(s) && prefix[n] == s[n] {
+			n++
+		}
+		prefix = prefix[:n]
+	}
+
+	// Back off to the start of a rune which the strings only partly share.
+	last := len(prefix) - 1
+	for last > 0 && !utf8.RuneStart(prefix[last]) {
+		last--
+	}
+	if last >= 0 && !utf8.FullRuneInString(prefix[last:]) {
+		prefix = prefix[:last]
+	}
+
+	return prefix
+}
+
+// LongestCommonSuffix returns the longest string which every string in list ends with.
+// The suffix never starts partway through a multi-byte character.
+func LongestCommonSuffix(list []string) string {
+	if len(list) == 0 {
+		return ""
+	}
+
+	suffix := list[0]
+	for _, s := range list[1:] {
+		n := 0
+		for n < len(suffix) && n < len(s) && suffix[len(suffix)-1-n] == s[len(s)-1-n] {
+			n++
+		}
+		suffix = suffix[len(suffix)-n:]
+	}
+
+	for len(suffix) > 0 && !utf8.RuneStart(suffix[0]) {
+		suffix = suffix[1:]
+	}
+
+	return suffix
+}
+
+// TrimCommonPrefix returns a copy of list with the LongestCommonPrefix of its strings
+// removed from each, such as the shared directory of a list of paths.
+func TrimCommonPrefix(list []string) []string {
+	prefix := LongestCommonPrefix(list)
+
+	trimmed := make([]string, len(list))
+	for i, s := range list {
+		trimmed[i] = s[len(prefix):]
+	}
+
+	return trimmed
+}
@@ -0,0 +1,160 @@
+/*
+   Copyright (c) 2020, btnmasher
+   All rights reserved.
+
+   Redistribution and use in source and binary forms, with or without modification, are permitted provided that
+   the following conditions are met:
+
+   1. Redistributions of source code must retain the above copyright notice, this list of conditions and the
+      following disclaimer.
+
+   2. Redistributions in binary form must reproduce the above copyright notice, this list of conditions and
+      the following disclaimer in the documentation and/or other materials provided with the distribution.
+
+   3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse or
+      promote products derived from this software without specific prior written permission.
+
+   THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED
+   WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+   PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR
+   ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+   TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+   HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+   NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+   POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package util
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// ConcurrentCounter is a concurrent-safe map[string]int64 of tallies. Updates to
+// existing keys only take the read lock and are applied atomically, so counting
+// the same set of keys from many goroutines does not serialize on the lock.
+type ConcurrentCounter struct {
+	data map[string]*atomic.Int64
+	sync.RWMutex
+}
+
+// NewConcurrentCounter initializes and returns a pointer to a new ConcurrentCounter instance.
+func NewConcurrentCounter() *ConcurrentCounter {
+	c := &ConcurrentCounter{
+		data: make(map[string]*atomic.Int64),
+	}
+	return c
+}
+
+// Incr adds delta to the count for the key, starting from zero if the key
+// does not exist yet. Returns the new count.
+func (c *ConcurrentCounter) Incr(key string, delta int64) int64 {
+	c.RLock()
+	if n, exists := c.data[key]; exists {
+		// The add happens under the read lock so it can't race with Del or Reset.
+		total := n.Add(delta)
+		c.RUnlock()
+		return total
+	}
+	c.RUnlock()
+
+	c.Lock()
+	defer c.Unlock()
+
+	n, exists := c.data[key]
+	if !exists {
+		n = &atomic.Int64{}
+		c.data[key] = n
+	}
+
+	return n.Add(delta)
+}
+
+// Decr subtracts delta from the count for the key, starting from zero if the key
+// does not exist yet. Returns the new count.
+func (c *ConcurrentCounter) Decr(key string, delta int64) int64 {
+	return c.Incr(key, -delta)
+}
+
+// Get returns the count for the key, or zero if the key does not exist.
+func (c *ConcurrentCounter) Get(key string) int64 {
+	c.RLock()
+	defer c.RUnlock()
+
+	if n, exists := c.data[key]; exists {
+		return n.Load()
+	}
+
+	return 0
+}
+
+// Exists is used by external callers to check if a key
+// exists in the counter and returns a boolean with the result.
+func (c *ConcurrentCounter) Exists(key string) bool {
+	c.RLock()
+	defer c.RUnlock()
+
+	_, exists := c.data[key]
+	return exists
+}
+
+// Del removes the key from the counter, returning its final count.
+func (c *ConcurrentCounter) Del(key string) int64 {
+	c.Lock()
+	defer c.Unlock()
+
+	n, exists := c.data[key]
+	if !exists {
+		return 0
+	}
+
+	delete(c.data, key)
+	return n.Load()
+}
+
+// Reset removes all of the keys from the counter, returning their final counts.
+func (c *ConcurrentCounter) Reset() map[string]int64 {
+	c.Lock()
+	defer c.Unlock()
+
+	snap := c.snapshot()
+	c.data = make(map[string]*atomic.Int64)
+
+	return snap
+}
+
+// Length returns the number of keys in the counter.
+func (c *ConcurrentCounter) Length() int {
+	c.RLock()
+	defer c.RUnlock()
+
+	return len(c.data)
+}
+
+// ForEach will call the provided function for each key in the ConcurrentCounter with its count.
+func (c *ConcurrentCounter) ForEach(do func(string, int64)) {
+	c.RLock()
+	defer c.RUnlock()
+
+	for key, n := range c.data {
+		do(key, n.Load())
+	}
+}
+
+// Snapshot returns a copy of the current counts.
+func (c *ConcurrentCounter) Snapshot() map[string]int64 {
+	c.RLock()
+	defer c.RUnlock()
+
+	return c.snapshot()
+}
+
+// snapshot is the implementation of Snapshot. Must be called with the lock held.
+func (c *ConcurrentCounter) snapshot() map[string]int64 {
+	snap := make(map[string]int64, len(c.data))
+	for key, n := range c.data {
+		snap[key] = n.Load()
+	}
+	return snap
+}
@@ -0,0 +1,164 @@
+/*
+   Copyright (c) 2020, btnmasher
+   All rights reserved.
+
+   Redistribution and use in source and binary forms, with or without modification, are permitted provided that
+   the following conditions are met:
+
+   1. Redistributions of source code must retain the above copyright notice, this list of conditions and the
+      following disclaimer.
+
+   2. Redistributions in binary form must reproduce the above copyright notice, this list of conditions and
+      the following disclaimer in the documentation and/or other materials provided with the distribution.
+
+   3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse or
+      promote products derived from this software without specific prior written permission.
+
+   THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED
+   WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+   PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR
+   ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+   TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+   HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+   NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+   POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package util
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// CopyOnWriteMapString is a concurrent-safe map[string]string for read-heavy workloads.
+// Reads load the current map with an atomic pointer and never take a lock, while each
+// write copies the whole map under a mutex and swaps the pointer. Writes therefore cost
+// O(n) and should be rare relative to reads.
+type CopyOnWriteMapString struct {
+	data atomic.Pointer[map[string]string]
+	mu   sync.Mutex // Serializes writers.
+}
+
+// NewCopyOnWriteMapString initializes and returns a pointer to a new CopyOnWriteMapString instance.
+func NewCopyOnWriteMapString() *CopyOnWriteMapString {
+	m := &CopyOnWriteMapString{}
+	data := make(map[string]string)
+	m.data.Store(&data)
+	return m
+}
+
+// load returns the current map, which must not be modified.
+func (m *CopyOnWriteMapString) load() map[string]string {
+	return *m.data.Load()
+}
+
+// update copies the current map, applies the change and publishes the copy.
+// If change returns an error the copy is discarded.
+func (m *CopyOnWriteMapString) update(change func(map[string]string) error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	current := m.load()
+	next := make(map[string]string, len(current)+1)
+	for key, val := range current {
+		next[key] = val
+	}
+
+	if err := change(next); err != nil {
+		return err
+	}
+
+	m.data.Store(&next)
+	return nil
+}
+
+// ForEach will call the provided function for each entry in the map as it was
+// when ForEach was called. Writes made during the iteration are not observed.
+func (m *CopyOnWriteMapString) ForEach(do func(string, string)) {
+	for key, val := range m.load() {
+		do(key, val)
+	}
+}
+
+// Length returns the length of the underlying map.
+func (m *CopyOnWriteMapString) Length() int {
+	return len(m.load())
+}
+
+// Add is used to add a key/value to the map.
+// Returns an error if the key already exists.
+func (m *CopyOnWriteMapString) Add(key string, value string) error {
+	return m.update(func(data map[string]string) error {
+		if _, exists := data[key]; exists {
+			return &KeyError{Op: "CopyOnWriteMapString: Cannot add map entry", Key: key, Err: ErrKeyExists}
+		}
+		data[key] = value
+		return nil
+	})
+}
+
+// Del is used to remove a key/value from the map.
+// Returns an error if the key does not exist.
+func (m *CopyOnWriteMapString) Del(key string) error {
+	return m.update(func(data map[string]string) error {
+		if _, exists := data[key]; !exists {
+			return &KeyError{Op: "CopyOnWriteMapString: Cannot delete map entry", Key: key, Err: ErrKeyNotFound}
+		}
+		delete(data, key)
+		return nil
+	})
+}
+
+// Get is used to get a key/value from the map.
+// Returns an error if the key does not exist.
+func (m *CopyOnWriteMapString) Get(key string) (string, error) {
+	v, exists := m.load()[key]
+
+	if !exists {
+		return "", &KeyError{Op: "CopyOnWriteMapString: Cannot get map value", Key: key, Err: ErrKeyNotFound}
+	}
+
+	return v, nil
+}
+
+// Set is used to change an existing key/value in the map.
+// Returns an error if the key does not exist.
+func (m *CopyOnWriteMapString) Set(key string, value string) error {
+	return m.update(func(data map[string]string) error {
+		if _, exists := data[key]; !exists {
+			return &KeyError{Op: "CopyOnWriteMapString: Cannot set map value", Key: key, Err: ErrKeyNotFound}
+		}
+		data[key] = value
+		return nil
+	})
+}
+
+// Exists is used by external callers to check if a value
+// exists in the map and returns a boolean with the result.
+func (m *CopyOnWriteMapString) Exists(key string) bool {
+	_, exists := m.load()[key]
+	return exists
+}
+
+// Snapshot returns a copy of the current map which the caller is free to modify.
+func (m *CopyOnWriteMapString) Snapshot() map[string]string {
+	current := m.load()
+
+	snap := make(map[string]string, len(current))
+	for key, val := range current {
+		snap[key] = val
+	}
+
+	return snap
+}
+
+// Update is used to add or change several key/values in the map with a single copy.
+func (m *CopyOnWriteMapString) Update(entries map[string]string) {
+	m.update(func(data map[string]string) error {
+		for key, val := range entries {
+			data[key] = val
+		}
+		return nil
+	})
+}
@@ -0,0 +1,55 @@
+/*
+   Copyright (c) 2020, btnmasher
+   All rights reserved.
+
+   Redistribution and use in source and binary forms, with or without modification, are permitted provided that
+   the following conditions are met:
+
+   1. Redistributions of source code must retain the above copyright notice, this list of conditions and the
+      following disclaimer.
+
+   2. Redistributions in binary form must reproduce the above copyright notice, this list of conditions and
+      the following disclaimer in the documentation and/or other materials provided with the distribution.
+
+   3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse or
+      promote products derived from this software without specific prior written permission.
+
+   THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED
+   WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+   PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR
+   ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+   TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+   HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+   NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+   POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package util
+
+import "bytes"
+
+// DefaultMaxRetainedCapacity is the largest Buffer kept by the default pool.
+const DefaultMaxRetainedCapacity = 64 << 10
+
+// defaultBufferPool is the process-wide pool behind GetBuffer and PutBuffer.
+var defaultBufferPool = NewBufferPoolWith(
+	WithMaxBuffers(DefaultMaxBuffers),
+	WithMaxRetainedCapacity(DefaultMaxRetainedCapacity),
+)
+
+// DefaultBufferPool returns the process-wide BufferPool used by GetBuffer and PutBuffer,
+// which holds up to DefaultMaxBuffers Buffers of at most DefaultMaxRetainedCapacity bytes.
+func DefaultBufferPool() *BufferPool {
+	return defaultBufferPool
+}
+
+// GetBuffer takes a Buffer from the default pool, so libraries can share
+// one pool without passing a *BufferPool around.
+func GetBuffer() *bytes.Buffer {
+	return defaultBufferPool.New()
+}
+
+// PutBuffer returns a Buffer taken by GetBuffer to the default pool.
+func PutBuffer(buf *bytes.Buffer) {
+	defaultBufferPool.Recycle(buf)
+}
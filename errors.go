@@ -0,0 +1,75 @@
+/*
+   Copyright (c) 2020, btnmasher
+   All rights reserved.
+
+   Redistribution and use in source and binary forms, with or without modification, are permitted provided that
+   the following conditions are met:
+
+   1. Redistributions of source code must retain the above copyright notice, this list of conditions and the
+      following disclaimer.
+
+   2. Redistributions in binary form must reproduce the above copyright notice, this list of conditions and
+      the following disclaimer in the documentation and/or other materials provided with the distribution.
+
+   3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse or
+      promote products derived from this software without specific prior written permission.
+
+   THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED
+   WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+   PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR
+   ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+   TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+   HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+   NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+   POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package util
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Sentinel errors returned by the map types in this package, wrapped in a
+// KeyError. Use errors.Is to check for them.
+var (
+	ErrKeyExists     = errors.New("key already exists")
+	ErrKeyNotFound   = errors.New("key does not exist")
+	ErrValueExists   = errors.New("value already exists")
+	ErrValueNotFound = errors.New("value does not exist")
+)
+
+// Sentinel errors returned by the pool types in this package.
+var (
+	ErrPoolClosed = errors.New("pool is closed")
+	ErrQueueFull  = errors.New("queue is full")
+)
+
+// Sentinel errors returned by the string helpers in this package.
+var (
+	ErrItemTooLong          = errors.New("item is longer than maxlength")
+	ErrUnknownVariable      = errors.New("unknown variable")
+	ErrUnterminatedVariable = errors.New("unterminated variable")
+	ErrUnterminatedQuote    = errors.New("unterminated quote")
+	ErrTrailingEscape       = errors.New("trailing backslash")
+	ErrEmptyCharset         = errors.New("charset is empty")
+)
+
+// KeyError records a failed map operation along with the key that caused it.
+// For the value-indexed operations of ConcurrentBiMap, Key holds the value.
+type KeyError struct {
+	Op  string
+	Key string
+	Err error
+}
+
+// Error returns the description of the failure.
+func (e *KeyError) Error() string {
+	return fmt.Sprintf("%s, %v: %q", e.Op, e.Err, e.Key)
+}
+
+// Unwrap returns the underlying sentinel error.
+func (e *KeyError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,291 @@
+/*
+   Copyright (c) 2020, btnmasher
+   All rights reserved.
+
+   Redistribution and use in source and binary forms, with or without modification, are permitted provided that
+   the following conditions are met:
+
+   1. Redistributions of source code must retain the above copyright notice, this list of conditions and the
+      following disclaimer.
+
+   2. Redistributions in binary form must reproduce the above copyright notice, this list of conditions and
+      the following disclaimer in the documentation and/or other materials provided with the distribution.
+
+   3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse or
+      promote products derived from this software without specific prior written permission.
+
+   THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED
+   WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+   PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR
+   ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+   TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+   HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+   NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+   POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package util
+
+import (
+	"sync"
+	"time"
+)
+
+// expiringEntry is a value held by ExpiringMapString along with its expiry time.
+// A zero expires time means the entry never expires.
+type expiringEntry struct {
+	value   string
+	expires time.Time
+}
+
+// expired reports whether the entry has expired as of now.
+func (e expiringEntry) expired(now time.Time) bool {
+	return !e.expires.IsZero() && !now.Before(e.expires)
+}
+
+// ExpiringMapString is a concurrent-safe map[string]string where entries may be
+// given a time to live. Expired entries are hidden from the API immediately and
+// removed from the map by a background janitor which sweeps at a fixed interval.
+type ExpiringMapString struct {
+	data    map[string]expiringEntry
+	onEvict func(string, string)
+	stop    chan struct{}
+	once    sync.Once
+	sync.RWMutex
+}
+
+// NewExpiringMapString initializes and returns a pointer to a new ExpiringMapString instance.
+// The janitor sweeps for expired entries every interval, calling onEvict (if not nil) for
+// each entry it removes. If interval is not positive, no janitor is started and expired
+// entries are only removed when Sweep is called. Stop should be called once the map
+// is no longer needed so the janitor goroutine can exit.
+func NewExpiringMapString(interval time.Duration, onEvict func(key string, value string)) *ExpiringMapString {
+	m := &ExpiringMapString{
+		data:    make(map[string]expiringEntry),
+		onEvict: onEvict,
+		stop:    make(chan struct{}),
+	}
+
+	if interval > 0 {
+		go m.janitor(interval)
+	}
+
+	return m
+}
+
+// janitor periodically sweeps the map until Stop is called.
+func (m *ExpiringMapString) janitor(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			m.Sweep()
+		case <-m.stop:
+			return
+		}
+	}
+}
+
+// Stop halts the background janitor. It is safe to call more than once.
+func (m *ExpiringMapString) Stop() {
+	m.once.Do(func() {
+		close(m.stop)
+	})
+}
+
+// Sweep removes all of the expired entries from the map, calling the OnEvict
+// callback for each of them after the lock has been released.
+func (m *ExpiringMapString) Sweep() {
+	now := time.Now()
+	evicted := make(map[string]string)
+
+	m.Lock()
+	for key, entry := range m.data {
+		if entry.expired(now) {
+			evicted[key] = entry.value
+			delete(m.data, key)
+		}
+	}
+	m.Unlock()
+
+	if m.onEvict != nil {
+		for key, val := range evicted {
+			m.onEvict(key, val)
+		}
+	}
+}
+
+// lookup returns the live entry for the key, if any. Must be called with the lock held.
+func (m *ExpiringMapString) lookup(key string, now time.Time) (expiringEntry, bool) {
+	entry, exists := m.data[key]
+	if !exists || entry.expired(now) {
+		return expiringEntry{}, false
+	}
+	return entry, true
+}
+
+// ForEach will call the provided function for each unexpired entry in the ExpiringMapString
+func (m *ExpiringMapString) ForEach(do func(string, string)) {
+	now := time.Now()
+
+	m.RLock()
+	defer m.RUnlock()
+
+	for key, entry := range m.data {
+		if !entry.expired(now) {
+			do(key, entry.value)
+		}
+	}
+}
+
+// ForEachUntil will call the provided function for each unexpired entry in the
+// ExpiringMapString until it returns true, at which point the iteration stops early.
+// Returns true if the iteration was stopped by the provided function.
+func (m *ExpiringMapString) ForEachUntil(do func(string, string) bool) bool {
+	now := time.Now()
+
+	m.RLock()
+	defer m.RUnlock()
+
+	for key, entry := range m.data {
+		if !entry.expired(now) && do(key, entry.value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Length returns the number of unexpired entries in the map.
+func (m *ExpiringMapString) Length() int {
+	now := time.Now()
+
+	m.RLock()
+	defer m.RUnlock()
+
+	count := 0
+	for _, entry := range m.data {
+		if !entry.expired(now) {
+			count++
+		}
+	}
+
+	return count
+}
+
+// Add is used to add a key/value to the map which never expires.
+// Returns an error if the key already exists.
+func (m *ExpiringMapString) Add(key string, value string) error {
+	return m.AddWithTTL(key, value, 0)
+}
+
+// AddWithTTL is used to add a key/value to the map which expires after ttl.
+// A ttl that is not positive means the entry never expires.
+// Returns an error if the key already exists. An expired entry for the key which
+// hasn't been swept yet is evicted, calling the OnEvict callback after the lock
+// has been released, as Sweep would.
+func (m *ExpiringMapString) AddWithTTL(key string, value string, ttl time.Duration) error {
+	now := time.Now()
+
+	m.Lock()
+
+	old, stale := m.data[key]
+	if stale && !old.expired(now) {
+		m.Unlock()
+		return &KeyError{Op: "ExpiringMapString: Cannot add map entry", Key: key, Err: ErrKeyExists}
+	}
+
+	entry := expiringEntry{value: value}
+	if ttl > 0 {
+		entry.expires = now.Add(ttl)
+	}
+
+	m.data[key] = entry
+	m.Unlock()
+
+	if stale && m.onEvict != nil {
+		m.onEvict(key, old.value)
+	}
+
+	return nil
+}
+
+// Del is used to remove a key/value from the map.
+// Returns an error if the key does not exist.
+func (m *ExpiringMapString) Del(key string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	if _, exists := m.lookup(key, time.Now()); !exists {
+		return &KeyError{Op: "ExpiringMapString: Cannot delete map entry", Key: key, Err: ErrKeyNotFound}
+	}
+
+	delete(m.data, key)
+	return nil
+}
+
+// Get is used to get a key/value from the map.
+// Returns an error if the key does not exist or has expired.
+func (m *ExpiringMapString) Get(key string) (string, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	entry, exists := m.lookup(key, time.Now())
+
+	if !exists {
+		return "", &KeyError{Op: "ExpiringMapString: Cannot get map value", Key: key, Err: ErrKeyNotFound}
+	}
+
+	return entry.value, nil
+}
+
+// Set is used to change an existing key/value in the map, keeping its expiry time.
+// Returns an error if the key does not exist or has expired.
+func (m *ExpiringMapString) Set(key string, value string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	entry, exists := m.lookup(key, time.Now())
+
+	if !exists {
+		return &KeyError{Op: "ExpiringMapString: Cannot set map value", Key: key, Err: ErrKeyNotFound}
+	}
+
+	entry.value = value
+	m.data[key] = entry
+
+	return nil
+}
+
+// Exists is used by external callers to check if an unexpired value
+// exists in the map and returns a boolean with the result.
+func (m *ExpiringMapString) Exists(key string) bool {
+	m.RLock()
+	defer m.RUnlock()
+
+	_, exists := m.lookup(key, time.Now())
+	return exists
+}
+
+// TTL returns the time remaining before the key expires, or zero if it never expires.
+// Returns an error if the key does not exist or has expired.
+func (m *ExpiringMapString) TTL(key string) (time.Duration, error) {
+	now := time.Now()
+
+	m.RLock()
+	defer m.RUnlock()
+
+	entry, exists := m.lookup(key, now)
+
+	if !exists {
+		return 0, &KeyError{Op: "ExpiringMapString: Cannot get map entry TTL", Key: key, Err: ErrKeyNotFound}
+	}
+
+	if entry.expires.IsZero() {
+		return 0, nil
+	}
+
+	return entry.expires.Sub(now), nil
+}
@@ -0,0 +1,92 @@
+/*
+   Copyright (c) 2020, btnmasher
+   All rights reserved.
+
+   Redistribution and use in source and binary forms, with or without modification, are permitted provided that
+   the following conditions are met:
+
+   1. Redistributions of source code must retain the above copyright notice, this list of conditions and the
+      following disclaimer.
+
+   2. Redistributions in binary form must reproduce the above copyright notice, this list of conditions and
+      the following disclaimer in the documentation and/or other materials provided with the distribution.
+
+   3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse or
+      promote products derived from this software without specific prior written permission.
+
+   THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED
+   WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+   PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR
+   ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+   TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+   HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+   NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+   POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package util
+
+import (
+	"compress/flate"
+	"io"
+)
+
+// FlatePool is a pool of flate.Writers of a fixed compression level, and of flate readers.
+type FlatePool struct {
+	writers *Pool[*flate.Writer]
+	readers *Pool[io.ReadCloser]
+}
+
+// NewFlatePool creates a new pool holding at most max flate.Writers and max flate readers.
+// The writers compress at the given level, which is one of the flate package's levels.
+// Returns an error if the level is invalid.
+func NewFlatePool(max, level int) (*FlatePool, error) {
+	if _, err := flate.NewWriter(io.Discard, level); err != nil {
+		return nil, err
+	}
+
+	return &FlatePool{
+		writers: NewPool(max,
+			func() *flate.Writer {
+				fw, _ := flate.NewWriter(io.Discard, level) // Level checked above.
+				return fw
+			},
+			func(fw *flate.Writer) { fw.Reset(io.Discard) },
+		),
+		readers: NewPool(max,
+			func() io.ReadCloser { return flate.NewReader(nil) },
+			nil,
+		),
+	}, nil
+}
+
+// NewWriter takes a flate.Writer from the pool, compressing to w.
+func (pool *FlatePool) NewWriter(w io.Writer) *flate.Writer {
+	fw := pool.writers.New()
+	fw.Reset(w)
+	return fw
+}
+
+// RecycleWriter returns a flate.Writer to the pool. Callers should Close the writer
+// first, as any data not yet written out is discarded.
+func (pool *FlatePool) RecycleWriter(fw *flate.Writer) {
+	pool.writers.Recycle(fw)
+}
+
+// NewReader takes a flate reader from the pool, decompressing from r.
+func (pool *FlatePool) NewReader(r io.Reader) io.ReadCloser {
+	fr := pool.readers.New()
+	fr.(flate.Resetter).Reset(r, nil) // Never fails for flate readers.
+	return fr
+}
+
+// RecycleReader returns a flate reader taken from NewReader to the pool.
+func (pool *FlatePool) RecycleReader(fr io.ReadCloser) {
+	pool.readers.Recycle(fr)
+}
+
+// Close empties the pool and makes later calls to RecycleWriter and RecycleReader do nothing.
+func (pool *FlatePool) Close() {
+	pool.writers.Close(nil)
+	pool.readers.Close(nil)
+}
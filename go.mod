@@ -1,3 +1,3 @@
 module github.com/btnmasher/util
 
-go 1.14
+go 1.23
@@ -0,0 +1,98 @@
+/*
+   Copyright (c) 2020, btnmasher
+   All rights reserved.
+
+   Redistribution and use in source and binary forms, with or without modification, are permitted provided that
+   the following conditions are met:
+
+   1. Redistributions of source code must retain the above copyright notice, this list of conditions and the
+      following disclaimer.
+
+   2. Redistributions in binary form must reproduce the above copyright notice, this list of conditions and
+      the following disclaimer in the documentation and/or other materials provided with the distribution.
+
+   3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse or
+      promote products derived from this software without specific prior written permission.
+
+   THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED
+   WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+   PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR
+   ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+   TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+   HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+   NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+   POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package util
+
+import (
+	"compress/gzip"
+	"io"
+)
+
+// GzipPool is a pool of gzip.Writers of a fixed compression level, and of gzip.Readers.
+type GzipPool struct {
+	writers *Pool[*gzip.Writer]
+	readers *Pool[*gzip.Reader]
+}
+
+// NewGzipPool creates a new pool holding at most max gzip.Writers and max gzip.Readers.
+// The writers compress at the given level, which is one of the gzip package's levels.
+// Returns an error if the level is invalid.
+func NewGzipPool(max, level int) (*GzipPool, error) {
+	if _, err := gzip.NewWriterLevel(io.Discard, level); err != nil {
+		return nil, err
+	}
+
+	return &GzipPool{
+		writers: NewPool(max,
+			func() *gzip.Writer {
+				zw, _ := gzip.NewWriterLevel(io.Discard, level) // Level checked above.
+				return zw
+			},
+			func(zw *gzip.Writer) { zw.Reset(io.Discard) },
+		),
+		readers: NewPool(max,
+			func() *gzip.Reader { return new(gzip.Reader) },
+			nil,
+		),
+	}, nil
+}
+
+// NewWriter takes a gzip.Writer from the pool, compressing to w.
+func (pool *GzipPool) NewWriter(w io.Writer) *gzip.Writer {
+	zw := pool.writers.New()
+	zw.Reset(w)
+	return zw
+}
+
+// RecycleWriter returns a gzip.Writer to the pool. Callers should Close the writer
+// first, as any data not yet written out is discarded.
+func (pool *GzipPool) RecycleWriter(zw *gzip.Writer) {
+	pool.writers.Recycle(zw)
+}
+
+// NewReader takes a gzip.Reader from the pool, decompressing from r.
+// Returns an error if the gzip header can't be read from r.
+func (pool *GzipPool) NewReader(r io.Reader) (*gzip.Reader, error) {
+	zr := pool.readers.New()
+
+	if err := zr.Reset(r); err != nil {
+		pool.readers.Recycle(zr)
+		return nil, err
+	}
+
+	return zr, nil
+}
+
+// RecycleReader returns a gzip.Reader to the pool.
+func (pool *GzipPool) RecycleReader(zr *gzip.Reader) {
+	pool.readers.Recycle(zr)
+}
+
+// Close empties the pool and makes later calls to RecycleWriter and RecycleReader do nothing.
+func (pool *GzipPool) Close() {
+	pool.writers.Close(nil)
+	pool.readers.Close(nil)
+}
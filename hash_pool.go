@@ -0,0 +1,66 @@
+/*
+   Copyright (c) 2020, btnmasher
+   All rights reserved.
+
+   Redistribution and use in source and binary forms, with or without modification, are permitted provided that
+   the following conditions are met:
+
+   1. Redistributions of source code must retain the above copyright notice, this list of conditions and the
+      following disclaimer.
+
+   2. Redistributions in binary form must reproduce the above copyright notice, this list of conditions and
+      the following disclaimer in the documentation and/or other materials provided with the distribution.
+
+   3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse or
+      promote products derived from this software without specific prior written permission.
+
+   THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED
+   WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+   PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR
+   ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+   TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+   HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+   NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+   POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package util
+
+import "hash"
+
+// HashPool is a pool of hash.Hash states made by a single constructor, such as sha256.New,
+// so hot paths don't allocate a new state for every sum.
+type HashPool struct {
+	hashes *Pool[hash.Hash]
+}
+
+// NewHashPool creates a new pool holding at most max hash.Hash states made by new.
+func NewHashPool(max int, new func() hash.Hash) *HashPool {
+	return &HashPool{
+		hashes: NewPool(max, new, func(h hash.Hash) { h.Reset() }),
+	}
+}
+
+// New takes a hash.Hash from the pool, ready to be written to.
+func (pool *HashPool) New() hash.Hash {
+	return pool.hashes.New()
+}
+
+// Recycle resets a hash.Hash and returns it to the pool.
+func (pool *HashPool) Recycle(h hash.Hash) {
+	pool.hashes.Recycle(h)
+}
+
+// SumPooled returns the checksum of data, computed with a hash.Hash from the pool.
+func (pool *HashPool) SumPooled(data []byte) []byte {
+	h := pool.hashes.New()
+	defer pool.hashes.Recycle(h)
+
+	h.Write(data) // Writes to a hash.Hash never return an error.
+	return h.Sum(nil)
+}
+
+// Close shuts the pool down, discarding the hash.Hash states waiting in it.
+func (pool *HashPool) Close() {
+	pool.hashes.Close(nil)
+}
@@ -0,0 +1,193 @@
+/*
+   Copyright (c) 2020, btnmasher
+   All rights reserved.
+
+   Redistribution and use in source and binary forms, with or without modification, are permitted provided that
+   the following conditions are met:
+
+   1. Redistributions of source code must retain the above copyright notice, this list of conditions and the
+      following disclaimer.
+
+   2. Redistributions in binary form must reproduce the above copyright notice, this list of conditions and
+      the following disclaimer in the documentation and/or other materials provided with the distribution.
+
+   3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse or
+      promote products derived from this software without specific prior written permission.
+
+   THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED
+   WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+   PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR
+   ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+   TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+   HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+   NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+   POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package util
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// byteUnits are the binary units used by HumanBytes, each 1024 times the one before.
+var byteUnits = []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB", "EiB"}
+
+// HumanBytes formats a number of bytes in the largest binary unit it fills,
+// with one decimal place, such as "1.4 GiB". Under 1 KiB it is a whole
+// number of bytes, such as "512 B".
+func HumanBytes(n int64) string {
+	sign := ""
+	abs := float64(n)
+	if n < 0 {
+		sign, abs = "-", -abs
+	}
+
+	if abs < 1024 {
+		return fmt.Sprintf("%s%d B", sign, int64(abs))
+	}
+
+	// Move up a unit when the size would round up to 1024.0 of the one below.
+	unit := 0
+	for math.Round(abs*10) >= 1024*10 && unit < len(byteUnits)-1 {
+		abs /= 1024
+		unit++
+	}
+
+	return fmt.Sprintf("%s%.1f %s", sign, abs, byteUnits[unit])
+}
+
+// ParseHumanBytes parses a size such as "1.4 GiB", "10MB" or "512". Binary units
+// (KiB, MiB, ...) and bare letters (K, M, ...) are powers of 1024, and decimal
+// units (KB, MB, ...) powers of 1000. Units are not case sensitive.
+func ParseHumanBytes(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	split := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.' && r != '-' && r != '+'
+	})
+	if split < 0 {
+		split = len(s)
+	}
+
+	num, err := strconv.ParseFloat(s[:split], 64)
+	if err != nil {
+		return 0, fmt.Errorf("ParseHumanBytes: Cannot parse size: %q", s)
+	}
+
+	unit := strings.ToUpper(strings.TrimSpace(s[split:]))
+	multiplier := 1.0
+
+	if unit != "" && unit != "B" {
+		power := strings.IndexByte("KMGTPE", unit[0]) + 1
+		base := 0.0
+		switch unit[1:] {
+		case "", "I", "IB":
+			base = 1024
+		case "B":
+			base = 1000
+		}
+		if power == 0 || base == 0 {
+			return 0, fmt.Errorf("ParseHumanBytes: Cannot parse size, unknown unit: %q", s)
+		}
+		multiplier = math.Pow(base, float64(power))
+	}
+
+	size := num * multiplier
+	if size >= math.MaxInt64 || size <= math.MinInt64 {
+		return 0, fmt.Errorf("ParseHumanBytes: Cannot parse size, out of range: %q", s)
+	}
+
+	return int64(math.Round(size)), nil
+}
+
+// durationUnits are the units used by HumanDuration, from largest to smallest.
+var durationUnits = []struct {
+	name string
+	size time.Duration
+}{
+	{"d", 24 * time.Hour},
+	{"h", time.Hour},
+	{"m", time.Minute},
+	{"s", time.Second},
+}
+
+// HumanDuration formats a duration in its two largest units, from days down to
+// seconds, such as "2h 3m" or "1d 4h", dropping the rest. Durations under a
+// second are formatted by time.Duration.String, such as "250ms".
+func HumanDuration(d time.Duration) string {
+	sign := ""
+	if d < 0 {
+		sign, d = "-", -d
+	}
+
+	if d < time.Second {
+		return sign + d.String()
+	}
+
+	var parts []string
+	for _, unit := range durationUnits {
+		if d >= unit.size || len(parts) > 0 {
+			count := d / unit.size
+			d -= count * unit.size
+			if count > 0 {
+				parts = append(parts, strconv.FormatInt(int64(count), 10)+unit.name)
+			}
+			if len(parts) == 2 || len(parts) > 0 && count == 0 {
+				break
+			}
+		}
+	}
+
+	return sign + strings.Join(parts, " ")
+}
+
+// ParseHumanDuration parses a duration such as "2h 3m" or "1d 4h". It accepts
+// everything time.ParseDuration does, along with days ("d") and spaces between
+// the parts.
+func ParseHumanDuration(s string) (time.Duration, error) {
+	rest := strings.ReplaceAll(strings.TrimSpace(s), " ", "")
+
+	sign := time.Duration(1)
+	if strings.HasPrefix(rest, "-") {
+		sign, rest = -1, rest[1:]
+	}
+	if rest == "" {
+		return 0, fmt.Errorf("ParseHumanDuration: Cannot parse duration: %q", s)
+	}
+
+	var total time.Duration
+	for rest != "" {
+		// Split off the next number and unit.
+		unitStart := strings.IndexFunc(rest, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+		if unitStart <= 0 {
+			return 0, fmt.Errorf("ParseHumanDuration: Cannot parse duration: %q", s)
+		}
+		unitEnd := strings.IndexFunc(rest[unitStart:], func(r rune) bool { return (r >= '0' && r <= '9') || r == '.' })
+		if unitEnd < 0 {
+			unitEnd = len(rest) - unitStart
+		}
+		part := rest[:unitStart+unitEnd]
+		rest = rest[unitStart+unitEnd:]
+
+		if part[unitStart:] == "d" {
+			days, err := strconv.ParseFloat(part[:unitStart], 64)
+			if err != nil {
+				return 0, fmt.Errorf("ParseHumanDuration: Cannot parse duration: %q", s)
+			}
+			total += time.Duration(days * float64(24*time.Hour))
+			continue
+		}
+
+		d, err := time.ParseDuration(part)
+		if err != nil {
+			return 0, fmt.Errorf("ParseHumanDuration: Cannot parse duration: %q", s)
+		}
+		total += d
+	}
+
+	return sign * total, nil
+}
@@ -0,0 +1,113 @@
+/*
+   Copyright (c) 2020, btnmasher
+   All rights reserved.
+
+   Redistribution and use in source and binary forms, with or without modification, are permitted provided that
+   the following conditions are met:
+
+   1. Redistributions of source code must retain the above copyright notice, this list of conditions and the
+      following disclaimer.
+
+   2. Redistributions in binary form must reproduce the above copyright notice, this list of conditions and
+      the following disclaimer in the documentation and/or other materials provided with the distribution.
+
+   3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse or
+      promote products derived from this software without specific prior written permission.
+
+   THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED
+   WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+   PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR
+   ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+   TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+   HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+   NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+   POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package util
+
+import (
+	"strings"
+	"sync"
+)
+
+// Interner deduplicates equal strings to a single shared instance, wrapped with a
+// concurrent-safe API, to save memory when the same tokens are parsed over and over.
+// The table is bounded: once it holds max strings, strings not already in it are
+// returned as they are rather than added.
+type Interner struct {
+	data map[string]string
+	max  int
+	sync.RWMutex
+}
+
+// NewInterner initializes and returns a pointer to a new Interner instance holding
+// at most max strings. A max of zero or less means there is no limit.
+func NewInterner(max int) *Interner {
+	return &Interner{
+		data: make(map[string]string),
+		max:  max,
+	}
+}
+
+// Intern returns the shared instance of s, adding a copy of s to the table if it
+// isn't there yet. The copy means an interned string never keeps a larger string
+// it was sliced from alive.
+func (in *Interner) Intern(s string) string {
+	in.RLock()
+	shared, exists := in.data[s]
+	in.RUnlock()
+
+	if exists {
+		return shared
+	}
+
+	return in.add(s)
+}
+
+// InternBytes is the same as Intern, but takes the string as bytes, and doesn't
+// allocate when the string is already in the table.
+func (in *Interner) InternBytes(b []byte) string {
+	in.RLock()
+	shared, exists := in.data[string(b)] // The compiler avoids copying b for the lookup.
+	in.RUnlock()
+
+	if exists {
+		return shared
+	}
+
+	return in.add(string(b))
+}
+
+// add adds a copy of s to the table, unless it is full, and returns the shared instance.
+func (in *Interner) add(s string) string {
+	in.Lock()
+	defer in.Unlock()
+
+	if shared, exists := in.data[s]; exists { // Added while we waited for the lock.
+		return shared
+	}
+	if in.max > 0 && len(in.data) >= in.max {
+		return s
+	}
+
+	shared := strings.Clone(s)
+	in.data[shared] = shared
+	return shared
+}
+
+// Len returns the number of strings in the table.
+func (in *Interner) Len() int {
+	in.RLock()
+	defer in.RUnlock()
+
+	return len(in.data)
+}
+
+// Reset empties the table, so it can take new strings again once it is full.
+func (in *Interner) Reset() {
+	in.Lock()
+	defer in.Unlock()
+
+	in.data = make(map[string]string)
+}
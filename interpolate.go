@@ -0,0 +1,101 @@
+/*
+   Copyright (c) 2020, btnmasher
+   All rights reserved.
+
+   Redistribution and use in source and binary forms, with or without modification, are permitted provided that
+   the following conditions are met:
+
+   1. Redistributions of source code must retain the above copyright notice, this list of conditions and the
+      following disclaimer.
+
+   2. Redistributions in binary form must reproduce the above copyright notice, this list of conditions and
+      the following disclaimer in the documentation and/or other materials provided with the distribution.
+
+   3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse or
+      promote products derived from this software without specific prior written permission.
+
+   THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED
+   WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+   PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR
+   ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+   TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+   HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+   NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+   POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package util
+
+import (
+	"fmt"
+	"strings"
+)
+
+// interpolateConfig collects the settings applied by InterpolateOptions.
+type interpolateConfig struct {
+	strict bool
+}
+
+// InterpolateOption configures Interpolate.
+type InterpolateOption func(*interpolateConfig)
+
+// WithStrict makes Interpolate return an error wrapping ErrUnknownVariable for a
+// variable which isn't in vars and has no default, or ErrUnterminatedVariable for a
+// "${" with no closing brace, rather than expanding or keeping them as they are.
+func WithStrict() InterpolateOption {
+	return func(c *interpolateConfig) {
+		c.strict = true
+	}
+}
+
+// Interpolate expands the variables in s from vars: ${name} is replaced by the value
+// of name, and ${name:-default} by the value of name, or by default if name is unset
+// or empty. Unknown variables expand to the empty string, unless WithStrict is given.
+// A "$${" is written out as a literal "${".
+func Interpolate(s string, vars map[string]string, opts ...InterpolateOption) (string, error) {
+	var config interpolateConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	var out strings.Builder
+
+	for {
+		start := strings.Index(s, "${")
+		if start < 0 {
+			out.WriteString(s)
+			return out.String(), nil
+		}
+
+		// An escaped "$${" is written out as "${".
+		if start > 0 && s[start-1] == '$' {
+			out.WriteString(s[:start-1])
+			out.WriteString("${")
+			s = s[start+2:]
+			continue
+		}
+
+		end := strings.IndexByte(s[start:], '}')
+		if end < 0 {
+			if config.strict {
+				return "", fmt.Errorf("Interpolate: Cannot expand variable, %w: %q", ErrUnterminatedVariable, s[start:])
+			}
+			out.WriteString(s)
+			return out.String(), nil
+		}
+
+		name, def, hasDefault := strings.Cut(s[start+2:start+end], ":-")
+		value, ok := vars[name]
+
+		switch {
+		case hasDefault && value == "":
+			value = def
+		case !ok && config.strict:
+			return "", fmt.Errorf("Interpolate: Cannot expand variable, %w: %q", ErrUnknownVariable, name)
+		}
+
+		out.WriteString(s[:start])
+		out.WriteString(value)
+		s = s[start+end+1:]
+	}
+}
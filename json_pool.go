@@ -0,0 +1,102 @@
+/*
+   Copyright (c) 2020, btnmasher
+   All rights reserved.
+
+   Redistribution and use in source and binary forms, with or without modification, are permitted provided that
+   the following conditions are met:
+
+   1. Redistributions of source code must retain the above copyright notice, this list of conditions and the
+      following disclaimer.
+
+   2. Redistributions in binary form must reproduce the above copyright notice, this list of conditions and
+      the following disclaimer in the documentation and/or other materials provided with the distribution.
+
+   3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse or
+      promote products derived from this software without specific prior written permission.
+
+   THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED
+   WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+   PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR
+   ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+   TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+   HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+   NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+   POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// bufferWriter is an io.Writer which forwards to a swappable Buffer, so a json.Encoder
+// created once can write into whichever pooled Buffer it is handed next.
+type bufferWriter struct {
+	buf *bytes.Buffer
+}
+
+// Write writes p to the current Buffer.
+func (w *bufferWriter) Write(p []byte) (int, error) {
+	return w.buf.Write(p)
+}
+
+// pooledEncoder is a json.Encoder along with the writer it is bound to.
+type pooledEncoder struct {
+	w   *bufferWriter
+	enc *json.Encoder
+}
+
+// EncoderPool pools json.Encoders and encodes into Buffers taken from a BufferPool,
+// so marshaling on hot paths doesn't allocate an encoder and output buffer each time.
+type EncoderPool struct {
+	buffers  *BufferPool
+	encoders *Pool[*pooledEncoder]
+}
+
+// NewEncoderPool creates a new EncoderPool holding at most max encoders, which encode
+// into Buffers from the given pool. The optional configure function is called on each
+// new encoder, for example to SetIndent or SetEscapeHTML.
+func NewEncoderPool(buffers *BufferPool, max int, configure func(*json.Encoder)) *EncoderPool {
+	return &EncoderPool{
+		buffers: buffers,
+		encoders: NewPool(max,
+			func() *pooledEncoder {
+				w := &bufferWriter{}
+				enc := json.NewEncoder(w)
+				if configure != nil {
+					configure(enc)
+				}
+				return &pooledEncoder{w: w, enc: enc}
+			},
+			func(e *pooledEncoder) { e.w.buf = nil },
+		),
+	}
+}
+
+// EncodeTo encodes v as JSON into buf using a pooled encoder. As with json.Encoder,
+// the output is followed by a newline.
+func (pool *EncoderPool) EncodeTo(buf *bytes.Buffer, v any) error {
+	e := pool.encoders.New()
+	defer pool.encoders.Recycle(e)
+
+	e.w.buf = buf
+	return e.enc.Encode(v)
+}
+
+// MarshalPooled encodes v as JSON like json.Marshal, but into a pooled Buffer. The
+// returned bytes are only valid until the returned release function is called, which
+// returns the Buffer to the pool. On error the Buffer has already been released.
+func (pool *EncoderPool) MarshalPooled(v any) ([]byte, func(), error) {
+	buf := pool.buffers.New()
+
+	if err := pool.EncodeTo(buf, v); err != nil {
+		pool.buffers.Recycle(buf)
+		return nil, nil, err
+	}
+
+	b := bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+
+	return b, func() { pool.buffers.Recycle(buf) }, nil
+}
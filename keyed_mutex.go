@@ -0,0 +1,98 @@
+/*
+   Copyright (c) 2020, btnmasher
+   All rights reserved.
+
+   Redistribution and use in source and binary forms, with or without modification, are permitted provided that
+   the following conditions are met:
+
+   1. Redistributions of source code must retain the above copyright notice, this list of conditions and the
+      following disclaimer.
+
+   2. Redistributions in binary form must reproduce the above copyright notice, this list of conditions and
+      the following disclaimer in the documentation and/or other materials provided with the distribution.
+
+   3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse or
+      promote products derived from this software without specific prior written permission.
+
+   THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED
+   WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+   PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR
+   ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+   TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+   HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+   NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+   POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package util
+
+import "sync"
+
+// DefaultStripeCount is the number of stripes used by NewKeyedMutex
+// when a non-positive stripe count is requested.
+const DefaultStripeCount = 64
+
+// KeyedMutex serializes operations on the same logical key without a single global
+// lock. Keys are hashed onto a fixed set of RWMutex stripes, so memory use is bounded
+// no matter how many keys are locked; the trade-off is that different keys may share
+// a stripe and occasionally contend with each other. Locks for several keys must not
+// be held at once, as two keys on the same stripe would deadlock.
+type KeyedMutex struct {
+	stripes []sync.RWMutex
+	mask    uint32
+}
+
+// NewKeyedMutex initializes and returns a pointer to a new KeyedMutex instance.
+// The stripe count is rounded up to the next power of two, or DefaultStripeCount
+// is used if stripes is not positive.
+func NewKeyedMutex(stripes int) *KeyedMutex {
+	if stripes <= 0 {
+		stripes = DefaultStripeCount
+	}
+
+	count := powerOfTwo(stripes)
+
+	return &KeyedMutex{
+		stripes: make([]sync.RWMutex, count),
+		mask:    uint32(count - 1),
+	}
+}
+
+// stripe returns the mutex responsible for the given key.
+func (km *KeyedMutex) stripe(key string) *sync.RWMutex {
+	return &km.stripes[hashKey(key)&km.mask]
+}
+
+// Lock locks the key for writing.
+func (km *KeyedMutex) Lock(key string) {
+	km.stripe(key).Lock()
+}
+
+// Unlock unlocks the key for writing.
+func (km *KeyedMutex) Unlock(key string) {
+	km.stripe(key).Unlock()
+}
+
+// RLock locks the key for reading.
+func (km *KeyedMutex) RLock(key string) {
+	km.stripe(key).RLock()
+}
+
+// RUnlock unlocks the key for reading.
+func (km *KeyedMutex) RUnlock(key string) {
+	km.stripe(key).RUnlock()
+}
+
+// Locker returns a sync.Locker which locks and unlocks the key for writing.
+func (km *KeyedMutex) Locker(key string) sync.Locker {
+	return km.stripe(key)
+}
+
+// Do runs the provided function while holding the write lock for the key.
+func (km *KeyedMutex) Do(key string, do func()) {
+	mu := km.stripe(key)
+	mu.Lock()
+	defer mu.Unlock()
+
+	do()
+}
@@ -0,0 +1,226 @@
+/*
+   Copyright (c) 2020, btnmasher
+   All rights reserved.
+
+   Redistribution and use in source and binary forms, with or without modification, are permitted provided that
+   the following conditions are met:
+
+   1. Redistributions of source code must retain the above copyright notice, this list of conditions and the
+      following disclaimer.
+
+   2. Redistributions in binary form must reproduce the above copyright notice, this list of conditions and
+      the following disclaimer in the documentation and/or other materials provided with the distribution.
+
+   3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse or
+      promote products derived from this software without specific prior written permission.
+
+   THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED
+   WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+   PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR
+   ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+   TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+   HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+   NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+   POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package util
+
+import (
+	"container/list"
+	"sync"
+)
+
+// lruEntry is the value held in each element of the LRUMapString recency list.
+type lruEntry struct {
+	key   string
+	value string
+}
+
+// LRUMapString is a concurrent-safe map[string]string bounded to a maximum number
+// of entries. When an insert would exceed the capacity, the least recently used entry
+// is evicted. Add, Set and Get count as uses; Exists, Peek and ForEach do not.
+type LRUMapString struct {
+	data     map[string]*list.Element
+	order    *list.List // Front is the most recently used.
+	capacity int
+	onEvict  func(string, string)
+	sync.Mutex
+}
+
+// NewLRUMapString initializes and returns a pointer to a new LRUMapString instance
+// holding at most capacity entries. The onEvict callback, if not nil, is called with
+// each entry evicted to make room, after the map's lock has been released.
+// A capacity that is not positive means the map is unbounded.
+func NewLRUMapString(capacity int, onEvict func(key string, value string)) *LRUMapString {
+	return &LRUMapString{
+		data:     make(map[string]*list.Element),
+		order:    list.New(),
+		capacity: capacity,
+		onEvict:  onEvict,
+	}
+}
+
+// evict removes the least recently used entries until the map is within capacity.
+// Must be called with the lock held. Returns the evicted entries.
+func (m *LRUMapString) evict() []lruEntry {
+	if m.capacity <= 0 {
+		return nil
+	}
+
+	var evicted []lruEntry
+	for len(m.data) > m.capacity {
+		elem := m.order.Back()
+		entry := m.order.Remove(elem).(lruEntry)
+		delete(m.data, entry.key)
+		evicted = append(evicted, entry)
+	}
+
+	return evicted
+}
+
+// evicted calls the OnEvict callback for each of the given entries.
+// Must be called without the lock held.
+func (m *LRUMapString) evicted(entries []lruEntry) {
+	if m.onEvict == nil {
+		return
+	}
+	for _, entry := range entries {
+		m.onEvict(entry.key, entry.value)
+	}
+}
+
+// Capacity returns the maximum number of entries the map will hold.
+func (m *LRUMapString) Capacity() int {
+	m.Lock()
+	defer m.Unlock()
+
+	return m.capacity
+}
+
+// Resize changes the maximum number of entries the map will hold, evicting the
+// least recently used entries if the map is now over capacity.
+func (m *LRUMapString) Resize(capacity int) {
+	m.Lock()
+	m.capacity = capacity
+	evicted := m.evict()
+	m.Unlock()
+
+	m.evicted(evicted)
+}
+
+// ForEach will call the provided function for each entry in the LRUMapString,
+// from the most to the least recently used, without affecting their recency.
+func (m *LRUMapString) ForEach(do func(string, string)) {
+	m.Lock()
+	defer m.Unlock()
+
+	for elem := m.order.Front(); elem != nil; elem = elem.Next() {
+		entry := elem.Value.(lruEntry)
+		do(entry.key, entry.value)
+	}
+}
+
+// Length returns the number of entries in the map.
+func (m *LRUMapString) Length() int {
+	m.Lock()
+	defer m.Unlock()
+
+	return len(m.data)
+}
+
+// Add is used to add a key/value to the map as the most recently used entry,
+// evicting the least recently used entry if the map is full.
+// Returns an error if the key already exists.
+func (m *LRUMapString) Add(key string, value string) error {
+	m.Lock()
+
+	if _, exists := m.data[key]; exists {
+		m.Unlock()
+		return &KeyError{Op: "LRUMapString: Cannot add map entry", Key: key, Err: ErrKeyExists}
+	}
+
+	m.data[key] = m.order.PushFront(lruEntry{key: key, value: value})
+	evicted := m.evict()
+	m.Unlock()
+
+	m.evicted(evicted)
+	return nil
+}
+
+// Del is used to remove a key/value from the map.
+// Returns an error if the key does not exist.
+func (m *LRUMapString) Del(key string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	elem, exists := m.data[key]
+
+	if !exists {
+		return &KeyError{Op: "LRUMapString: Cannot delete map entry", Key: key, Err: ErrKeyNotFound}
+	}
+
+	m.order.Remove(elem)
+	delete(m.data, key)
+
+	return nil
+}
+
+// Get is used to get a key/value from the map, marking it as the most recently used.
+// Returns an error if the key does not exist.
+func (m *LRUMapString) Get(key string) (string, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	elem, exists := m.data[key]
+
+	if !exists {
+		return "", &KeyError{Op: "LRUMapString: Cannot get map value", Key: key, Err: ErrKeyNotFound}
+	}
+
+	m.order.MoveToFront(elem)
+	return elem.Value.(lruEntry).value, nil
+}
+
+// Peek is used to get a key/value from the map without affecting its recency.
+// Returns an error if the key does not exist.
+func (m *LRUMapString) Peek(key string) (string, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	elem, exists := m.data[key]
+
+	if !exists {
+		return "", &KeyError{Op: "LRUMapString: Cannot get map value", Key: key, Err: ErrKeyNotFound}
+	}
+
+	return elem.Value.(lruEntry).value, nil
+}
+
+// Set is used to change an existing key/value in the map, marking it as the most recently used.
+// Returns an error if the key does not exist.
+func (m *LRUMapString) Set(key string, value string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	elem, exists := m.data[key]
+
+	if !exists {
+		return &KeyError{Op: "LRUMapString: Cannot set map value", Key: key, Err: ErrKeyNotFound}
+	}
+
+	elem.Value = lruEntry{key: key, value: value}
+	m.order.MoveToFront(elem)
+
+	return nil
+}
+
+// Exists is used by external callers to check if a value exists in the map
+// and returns a boolean with the result. It does not affect the entry's recency.
+func (m *LRUMapString) Exists(key string) bool {
+	m.Lock()
+	defer m.Unlock()
+
+	_, exists := m.data[key]
+	return exists
+}
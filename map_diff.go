@@ -0,0 +1,92 @@
+/*
+   Copyright (c) 2020, btnmasher
+   All rights reserved.
+
+   Redistribution and use in source and binary forms, with or without modification, are permitted provided that
+   the following conditions are met:
+
+   1. Redistributions of source code must retain the above copyright notice, this list of conditions and the
+      following disclaimer.
+
+   2. Redistributions in binary form must reproduce the above copyright notice, this list of conditions and
+      the following disclaimer in the documentation and/or other materials provided with the distribution.
+
+   3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse or
+      promote products derived from this software without specific prior written permission.
+
+   THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED
+   WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+   PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR
+   ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+   TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+   HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+   NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+   POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package util
+
+import "sort"
+
+// MapDiff describes the differences between two ConcurrentMapStrings, as the keys
+// which would need to change to turn the first map into the second. Each list is sorted.
+type MapDiff struct {
+	Added   []string // Keys only in the second map.
+	Removed []string // Keys only in the first map.
+	Changed []string // Keys in both maps with different values.
+}
+
+// Empty reports whether the two maps compared were equal.
+func (d MapDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// Equal reports whether the map holds exactly the same key/values as other.
+// Both maps are compared by snapshot, so the two locks are never held at once.
+func (m *ConcurrentMapString) Equal(other *ConcurrentMapString) bool {
+	a, b := m.Snapshot(), other.Snapshot()
+
+	if len(a) != len(b) {
+		return false
+	}
+
+	for key, val := range a {
+		if v, exists := b[key]; !exists || v != val {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Diff returns the keys which were added, removed or changed going from the map to other,
+// for example from the desired state to the actual state. Both maps are compared by
+// snapshot, so the two locks are never held at once.
+func (m *ConcurrentMapString) Diff(other *ConcurrentMapString) MapDiff {
+	a, b := m.Snapshot(), other.Snapshot()
+
+	var diff MapDiff
+
+	for key, val := range a {
+		v, exists := b[key]
+
+		switch {
+		case !exists:
+			diff.Removed = append(diff.Removed, key)
+		case v != val:
+			diff.Changed = append(diff.Changed, key)
+		}
+	}
+
+	for key := range b {
+		if _, exists := a[key]; !exists {
+			diff.Added = append(diff.Added, key)
+		}
+	}
+
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Strings(diff.Changed)
+
+	return diff
+}
@@ -0,0 +1,148 @@
+/*
+   Copyright (c) 2020, btnmasher
+   All rights reserved.
+
+   Redistribution and use in source and binary forms, with or without modification, are permitted provided that
+   the following conditions are met:
+
+   1. Redistributions of source code must retain the above copyright notice, this list of conditions and the
+      following disclaimer.
+
+   2. Redistributions in binary form must reproduce the above copyright notice, this list of conditions and
+      the following disclaimer in the documentation and/or other materials provided with the distribution.
+
+   3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse or
+      promote products derived from this software without specific prior written permission.
+
+   THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED
+   WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+   PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR
+   ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+   TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+   HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+   NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+   POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package util
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// JournalEntry records a single mutation of a ConcurrentMapString. Old is empty
+// for MapEventAdd and New is empty for MapEventDel.
+type JournalEntry struct {
+	Op   MapEventOp
+	Key  string
+	Old  string
+	New  string
+	Time time.Time
+}
+
+// mapJournal is a fixed size ring of the most recent mutations of a map. A nil
+// *mapJournal is valid and records nothing, which is how the journal is disabled.
+type mapJournal struct {
+	entries []JournalEntry
+	next    int
+	full    bool
+}
+
+// record appends a mutation to the ring, overwriting the oldest entry once it is full.
+// Must be called with the map's write lock held.
+func (j *mapJournal) record(op MapEventOp, key string, old string, new string) {
+	if j == nil {
+		return
+	}
+
+	j.entries[j.next] = JournalEntry{
+		Op:   op,
+		Key:  key,
+		Old:  old,
+		New:  new,
+		Time: time.Now(),
+	}
+
+	j.next++
+	if j.next == len(j.entries) {
+		j.next = 0
+		j.full = true
+	}
+}
+
+// list returns the recorded entries, oldest first. Must be called with the map's lock held.
+func (j *mapJournal) list() []JournalEntry {
+	if j == nil {
+		return nil
+	}
+
+	if !j.full {
+		return append([]JournalEntry(nil), j.entries[:j.next]...)
+	}
+
+	list := make([]JournalEntry, 0, len(j.entries))
+	list = append(list, j.entries[j.next:]...)
+	return append(list, j.entries[:j.next]...)
+}
+
+// EnableJournal starts recording the most recent size mutations of the map, replacing
+// any existing journal. A size that is not positive disables the journal.
+func (m *ConcurrentMapString) EnableJournal(size int) {
+	m.Lock()
+	defer m.Unlock()
+
+	if size <= 0 {
+		m.journal = nil
+		return
+	}
+
+	m.journal = &mapJournal{
+		entries: make([]JournalEntry, size),
+	}
+}
+
+// Journal returns a copy of the recorded mutations, oldest first.
+// Returns nil if the journal is not enabled.
+func (m *ConcurrentMapString) Journal() []JournalEntry {
+	m.RLock()
+	defer m.RUnlock()
+
+	return m.journal.list()
+}
+
+// ExportJournal writes the recorded mutations to w as JSON, one entry per line, oldest first.
+func (m *ConcurrentMapString) ExportJournal(w io.Writer) error {
+	enc := json.NewEncoder(w)
+
+	for _, entry := range m.Journal() {
+		if err := enc.Encode(entry); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReplayJournal applies the recorded mutations, oldest first, to target. For the result
+// to match this map, target must hold what this map held before the oldest entry.
+func (m *ConcurrentMapString) ReplayJournal(target *ConcurrentMapString) {
+	entries := m.Journal()
+
+	target.Lock()
+	defer target.Unlock()
+
+	for _, entry := range entries {
+		key := target.normalizeKey(entry.Key)
+
+		switch entry.Op {
+		case MapEventAdd, MapEventSet:
+			target.store(key, entry.New)
+		case MapEventDel:
+			if _, exists := target.data[key]; exists {
+				target.remove(key)
+			}
+		}
+	}
+}
@@ -0,0 +1,134 @@
+/*
+   Copyright (c) 2020, btnmasher
+   All rights reserved.
+
+   Redistribution and use in source and binary forms, with or without modification, are permitted provided that
+   the following conditions are met:
+
+   1. Redistributions of source code must retain the above copyright notice, this list of conditions and the
+      following disclaimer.
+
+   2. Redistributions in binary form must reproduce the above copyright notice, this list of conditions and
+      the following disclaimer in the documentation and/or other materials provided with the distribution.
+
+   3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse or
+      promote products derived from this software without specific prior written permission.
+
+   THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED
+   WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+   PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR
+   ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+   TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+   HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+   NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+   POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package util
+
+import "sync/atomic"
+
+// MapStats is a point in time copy of the operation counters of a ConcurrentMapString.
+// Adds, Sets and Dels count the entries actually written or removed, no matter which
+// method did so; failed operations are not counted.
+type MapStats struct {
+	Gets   uint64
+	Hits   uint64
+	Misses uint64
+	Adds   uint64
+	Sets   uint64
+	Dels   uint64
+}
+
+// HitRatio returns the fraction of lookups which found their key, or zero if there were none.
+func (s MapStats) HitRatio() float64 {
+	if s.Gets == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Gets)
+}
+
+// mapStats holds the live counters of a ConcurrentMapString. A nil *mapStats
+// is valid and records nothing, which is how stats are disabled.
+type mapStats struct {
+	hits   atomic.Uint64
+	misses atomic.Uint64
+	adds   atomic.Uint64
+	sets   atomic.Uint64
+	dels   atomic.Uint64
+}
+
+// recordGet counts a lookup as either a hit or a miss.
+func (s *mapStats) recordGet(hit bool) {
+	if s == nil {
+		return
+	}
+	if hit {
+		s.hits.Add(1)
+	} else {
+		s.misses.Add(1)
+	}
+}
+
+// recordAdd counts an entry being added.
+func (s *mapStats) recordAdd() {
+	if s != nil {
+		s.adds.Add(1)
+	}
+}
+
+// recordSet counts an existing entry being changed.
+func (s *mapStats) recordSet() {
+	if s != nil {
+		s.sets.Add(1)
+	}
+}
+
+// recordDel counts an entry being removed.
+func (s *mapStats) recordDel() {
+	if s != nil {
+		s.dels.Add(1)
+	}
+}
+
+// NewConcurrentMapStringWithStats initializes and returns a pointer to a new
+// ConcurrentMapString instance which counts its operations, see Stats.
+func NewConcurrentMapStringWithStats() *ConcurrentMapString {
+	m := NewConcurrentMapString()
+	m.stats = &mapStats{}
+	return m
+}
+
+// Stats returns the current operation counters of the map. The result is
+// always zero for maps not created by NewConcurrentMapStringWithStats.
+func (m *ConcurrentMapString) Stats() MapStats {
+	s := m.stats
+	if s == nil {
+		return MapStats{}
+	}
+
+	hits, misses := s.hits.Load(), s.misses.Load()
+
+	return MapStats{
+		Gets:   hits + misses,
+		Hits:   hits,
+		Misses: misses,
+		Adds:   s.adds.Load(),
+		Sets:   s.sets.Load(),
+		Dels:   s.dels.Load(),
+	}
+}
+
+// ResetStats sets all of the operation counters of the map back to zero.
+func (m *ConcurrentMapString) ResetStats() {
+	s := m.stats
+	if s == nil {
+		return
+	}
+
+	s.hits.Store(0)
+	s.misses.Store(0)
+	s.adds.Store(0)
+	s.sets.Store(0)
+	s.dels.Store(0)
+}
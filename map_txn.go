@@ -0,0 +1,138 @@
+/*
+   Copyright (c) 2020, btnmasher
+   All rights reserved.
+
+   Redistribution and use in source and binary forms, with or without modification, are permitted provided that
+   the following conditions are met:
+
+   1. Redistributions of source code must retain the above copyright notice, this list of conditions and the
+      following disclaimer.
+
+   2. Redistributions in binary form must reproduce the above copyright notice, this list of conditions and
+      the following disclaimer in the documentation and/or other materials provided with the distribution.
+
+   3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse or
+      promote products derived from this software without specific prior written permission.
+
+   THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED
+   WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+   PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR
+   ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+   TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+   HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+   NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+   POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package util
+
+// MutableView is the view of a ConcurrentMapString given to a Txn function. Reads see
+// the map with the transaction's own changes applied; the changes are only written to
+// the map if the function succeeds. A MutableView must not be used after Txn returns.
+type MutableView struct {
+	m       *ConcurrentMapString
+	changes map[string]*string // A nil value marks a deleted key.
+}
+
+// lookup returns the value of the key as seen by the transaction.
+func (v *MutableView) lookup(key string) (string, bool) {
+	key = v.m.normalizeKey(key)
+
+	if val, changed := v.changes[key]; changed {
+		if val == nil {
+			return "", false
+		}
+		return *val, true
+	}
+
+	val, exists := v.m.data[key]
+	return val, exists
+}
+
+// Get is used to get a key/value as seen by the transaction.
+// Returns a KeyError wrapping ErrKeyNotFound if the key does not exist.
+func (v *MutableView) Get(key string) (string, error) {
+	val, exists := v.lookup(key)
+
+	if !exists {
+		return "", &KeyError{Op: "ConcurrentMapString: Cannot get map value", Key: key, Err: ErrKeyNotFound}
+	}
+
+	return val, nil
+}
+
+// Exists is used to check if a key exists as seen by the transaction.
+func (v *MutableView) Exists(key string) bool {
+	_, exists := v.lookup(key)
+	return exists
+}
+
+// Add is used to add a key/value within the transaction.
+// Returns a KeyError wrapping ErrKeyExists if the key already exists.
+func (v *MutableView) Add(key string, value string) error {
+	key = v.m.normalizeKey(key)
+
+	if _, exists := v.lookup(key); exists {
+		return &KeyError{Op: "ConcurrentMapString: Cannot add map entry", Key: key, Err: ErrKeyExists}
+	}
+
+	v.changes[key] = &value
+	return nil
+}
+
+// Set is used to change an existing key/value within the transaction.
+// Returns a KeyError wrapping ErrKeyNotFound if the key does not exist.
+func (v *MutableView) Set(key string, value string) error {
+	key = v.m.normalizeKey(key)
+
+	if _, exists := v.lookup(key); !exists {
+		return &KeyError{Op: "ConcurrentMapString: Cannot set map value", Key: key, Err: ErrKeyNotFound}
+	}
+
+	v.changes[key] = &value
+	return nil
+}
+
+// Del is used to remove a key/value within the transaction.
+// Returns a KeyError wrapping ErrKeyNotFound if the key does not exist.
+func (v *MutableView) Del(key string) error {
+	key = v.m.normalizeKey(key)
+
+	if _, exists := v.lookup(key); !exists {
+		return &KeyError{Op: "ConcurrentMapString: Cannot delete map entry", Key: key, Err: ErrKeyNotFound}
+	}
+
+	v.changes[key] = nil
+	return nil
+}
+
+// Txn runs the provided function with a view of the map under a single acquisition of
+// the write lock, so no other caller can observe the map part way through its changes.
+// If the function returns an error, none of its changes are applied and the error is
+// returned; otherwise all of them are written to the map.
+func (m *ConcurrentMapString) Txn(do func(view *MutableView) error) error {
+	m.Lock()
+	defer m.Unlock()
+
+	view := &MutableView{
+		m:       m,
+		changes: make(map[string]*string),
+	}
+
+	if err := do(view); err != nil {
+		return err
+	}
+
+	for key, val := range view.changes {
+		_, exists := m.data[key]
+
+		switch {
+		case val != nil:
+			m.store(key, *val)
+		case exists:
+			m.remove(key)
+		}
+	}
+
+	return nil
+}
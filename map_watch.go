@@ -0,0 +1,155 @@
+/*
+   Copyright (c) 2020, btnmasher
+   All rights reserved.
+
+   Redistribution and use in source and binary forms, with or without modification, are permitted provided that
+   the following conditions are met:
+
+   1. Redistributions of source code must retain the above copyright notice, this list of conditions and the
+      following disclaimer.
+
+   2. Redistributions in binary form must reproduce the above copyright notice, this list of conditions and
+      the following disclaimer in the documentation and/or other materials provided with the distribution.
+
+   3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse or
+      promote products derived from this software without specific prior written permission.
+
+   THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED
+   WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+   PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR
+   ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+   TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+   HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+   NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+   POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package util
+
+import "fmt"
+
+// WatchBufferSize is the number of events buffered for each watcher. Events sent
+// to a watcher whose buffer is full are dropped rather than blocking the writer.
+const WatchBufferSize = 64
+
+// MapEventOp identifies the kind of mutation described by a MapEvent.
+type MapEventOp int
+
+// The kinds of mutation reported to watchers.
+const (
+	MapEventAdd MapEventOp = iota
+	MapEventSet
+	MapEventDel
+)
+
+// String returns the name of the operation.
+func (op MapEventOp) String() string {
+	switch op {
+	case MapEventAdd:
+		return "Add"
+	case MapEventSet:
+		return "Set"
+	case MapEventDel:
+		return "Del"
+	default:
+		return "Unknown"
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler, encoding the operation by name.
+func (op MapEventOp) MarshalText() ([]byte, error) {
+	return []byte(op.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, decoding an operation name.
+func (op *MapEventOp) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "Add":
+		*op = MapEventAdd
+	case "Set":
+		*op = MapEventSet
+	case "Del":
+		*op = MapEventDel
+	default:
+		return fmt.Errorf("MapEventOp: Cannot decode operation, unknown name: %q", text)
+	}
+	return nil
+}
+
+// MapEvent describes a single mutation of a watched map. For MapEventDel
+// the Value is the value which was removed.
+type MapEvent struct {
+	Op    MapEventOp
+	Key   string
+	Value string
+}
+
+// mapWatcher is a registered subscriber to map mutations.
+type mapWatcher struct {
+	key    string
+	all    bool
+	events chan MapEvent
+}
+
+// notify sends the event to all of the interested watchers without blocking.
+// Must be called with the lock held.
+func (m *ConcurrentMapString) notify(op MapEventOp, key string, value string) {
+	if len(m.watchers) == 0 {
+		return
+	}
+
+	event := MapEvent{Op: op, Key: key, Value: value}
+
+	for w := range m.watchers {
+		if !w.all && w.key != key {
+			continue
+		}
+
+		select {
+		case w.events <- event:
+		default: // The watcher isn't keeping up, drop the event.
+		}
+	}
+}
+
+// watch registers a new watcher and returns its channel and cancel function.
+func (m *ConcurrentMapString) watch(key string, all bool) (<-chan MapEvent, func()) {
+	w := &mapWatcher{
+		key:    key,
+		all:    all,
+		events: make(chan MapEvent, WatchBufferSize),
+	}
+
+	m.Lock()
+	if m.watchers == nil {
+		m.watchers = make(map[*mapWatcher]struct{})
+	}
+	m.watchers[w] = struct{}{}
+	m.Unlock()
+
+	cancel := func() {
+		m.Lock()
+		defer m.Unlock()
+
+		if _, exists := m.watchers[w]; exists {
+			delete(m.watchers, w)
+			close(w.events)
+		}
+	}
+
+	return w.events, cancel
+}
+
+// Watch returns a channel of events for mutations of the given key, and a function
+// which stops the watch and closes the channel. Events are delivered without blocking
+// the writer, so a watcher which falls more than WatchBufferSize events behind
+// will miss events. UnmarshalJSON and LoadFrom report the entries they change.
+func (m *ConcurrentMapString) Watch(key string) (<-chan MapEvent, func()) {
+	return m.watch(m.normalizeKey(key), false)
+}
+
+// WatchAll returns a channel of events for mutations of any key in the map, and a
+// function which stops the watch and closes the channel. See Watch for delivery semantics.
+func (m *ConcurrentMapString) WatchAll() (<-chan MapEvent, func()) {
+	return m.watch("", true)
+}
@@ -0,0 +1,162 @@
+/*
+   Copyright (c) 2020, btnmasher
+   All rights reserved.
+
+   Redistribution and use in source and binary forms, with or without modification, are permitted provided that
+   the following conditions are met:
+
+   1. Redistributions of source code must retain the above copyright notice, this list of conditions and the
+      following disclaimer.
+
+   2. Redistributions in binary form must reproduce the above copyright notice, this list of conditions and
+      the following disclaimer in the documentation and/or other materials provided with the distribution.
+
+   3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse or
+      promote products derived from this software without specific prior written permission.
+
+   THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED
+   WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+   PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR
+   ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+   TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+   HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+   NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+   POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package util
+
+import "sync"
+
+// ConcurrentMultiMap is a map[string][]string wrapped with a concurrent-safe API,
+// where each key holds an ordered list of values. A key exists for as long as
+// it holds at least one value.
+type ConcurrentMultiMap struct {
+	data map[string][]string
+	sync.RWMutex
+}
+
+// NewConcurrentMultiMap initializes and returns a pointer to a new ConcurrentMultiMap instance.
+func NewConcurrentMultiMap() *ConcurrentMultiMap {
+	m := &ConcurrentMultiMap{
+		data: make(map[string][]string),
+	}
+	return m
+}
+
+// ForEach will call the provided function for each key in the ConcurrentMultiMap
+// with a copy of its values.
+func (m *ConcurrentMultiMap) ForEach(do func(string, []string)) {
+	m.RLock()
+	defer m.RUnlock()
+
+	for key, vals := range m.data {
+		do(key, append([]string(nil), vals...))
+	}
+}
+
+// Length returns the number of keys in the map.
+func (m *ConcurrentMultiMap) Length() int {
+	m.RLock()
+	defer m.RUnlock()
+
+	return len(m.data)
+}
+
+// Count returns the number of values held by the key.
+func (m *ConcurrentMultiMap) Count(key string) int {
+	m.RLock()
+	defer m.RUnlock()
+
+	return len(m.data[key])
+}
+
+// Append is used to add a value to the end of the key's list of values,
+// creating the key if it does not exist.
+func (m *ConcurrentMultiMap) Append(key string, value string) {
+	m.Lock()
+	defer m.Unlock()
+
+	m.data[key] = append(m.data[key], value)
+}
+
+// Remove is used to remove the first occurrence of a value from the key's list of
+// values, removing the key once it holds no values.
+// Returns an error if the key does not hold the value.
+func (m *ConcurrentMultiMap) Remove(key string, value string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	vals := m.data[key]
+
+	for i, v := range vals {
+		if v != value {
+			continue
+		}
+
+		if len(vals) == 1 {
+			delete(m.data, key)
+			return nil
+		}
+
+		m.data[key] = append(vals[:i], vals[i+1:]...)
+		return nil
+	}
+
+	return &KeyError{Op: "ConcurrentMultiMap: Cannot remove map value", Key: key, Err: ErrValueNotFound}
+}
+
+// Del is used to remove a key and all of its values from the map.
+// Returns an error if the key does not exist.
+func (m *ConcurrentMultiMap) Del(key string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	_, exists := m.data[key]
+
+	if !exists {
+		return &KeyError{Op: "ConcurrentMultiMap: Cannot delete map entry", Key: key, Err: ErrKeyNotFound}
+	}
+
+	delete(m.data, key)
+
+	return nil
+}
+
+// Get is used to get a copy of the values held by the key, or nil if the key does not exist.
+func (m *ConcurrentMultiMap) Get(key string) []string {
+	m.RLock()
+	defer m.RUnlock()
+
+	vals, exists := m.data[key]
+
+	if !exists {
+		return nil
+	}
+
+	return append([]string(nil), vals...)
+}
+
+// Exists is used by external callers to check if a key
+// exists in the map and returns a boolean with the result.
+func (m *ConcurrentMultiMap) Exists(key string) bool {
+	m.RLock()
+	defer m.RUnlock()
+
+	_, exists := m.data[key]
+	return exists
+}
+
+// Contains is used to check if the key holds the given value.
+func (m *ConcurrentMultiMap) Contains(key string, value string) bool {
+	m.RLock()
+	defer m.RUnlock()
+
+	for _, v := range m.data[key] {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
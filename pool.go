@@ -0,0 +1,123 @@
+/*
+   Copyright (c) 2020, btnmasher
+   All rights reserved.
+
+   Redistribution and use in source and binary forms, with or without modification, are permitted provided that
+   the following conditions are met:
+
+   1. Redistributions of source code must retain the above copyright notice, this list of conditions and the
+      following disclaimer.
+
+   2. Redistributions in binary form must reproduce the above copyright notice, this list of conditions and
+      the following disclaimer in the documentation and/or other materials provided with the distribution.
+
+   3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse or
+      promote products derived from this software without specific prior written permission.
+
+   THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED
+   WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+   PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR
+   ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+   TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+   HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+   NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+   POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package util
+
+import "sync/atomic"
+
+// Pool holds reusable objects of any type in a Channel as a queue, with the same
+// semantics as BufferPool: New falls back to constructing an object when the pool
+// is empty, and Recycle drops the object when the pool is full.
+type Pool[T any] struct {
+	items   chan T
+	new     func() T
+	reset   func(T)
+	release atomic.Pointer[func(T)] // Passed to Close, stored before closed is set.
+	closed  atomic.Bool
+}
+
+// NewPool creates a new object pool holding at most max objects. The new function
+// constructs an object when the pool is empty, and the optional reset function
+// is called on each object as it is recycled, to clear it for the next user.
+func NewPool[T any](max int, new func() T, reset func(T)) *Pool[T] {
+	return &Pool[T]{
+		items: make(chan T, max),
+		new:   new,
+		reset: reset,
+	}
+}
+
+// Warmup fills the Pool with the specified number of new objects
+// up to the maximum capacity of the internal channel.
+func (pool *Pool[T]) Warmup(num int) {
+	for i := 0; i < num; i++ {
+		select {
+		case pool.items <- pool.new(): // Add the new object to the pool.
+		default: // We're full now because we got blocked trying to add that object.
+			return
+		}
+	}
+}
+
+// New takes an object from the pool, constructing one if the pool is empty.
+func (pool *Pool[T]) New() (item T) {
+	select {
+	case item = <-pool.items:
+	default:
+		item = pool.new()
+	}
+	return
+}
+
+// Recycle resets an object and returns it to the pool.
+func (pool *Pool[T]) Recycle(item T) {
+	if pool.closed.Load() {
+		return
+	}
+	if pool.reset != nil {
+		pool.reset(item)
+	}
+	select {
+	case pool.items <- item:
+		// Close may have drained the pool just before this object was added.
+		if pool.closed.Load() {
+			pool.drain(*pool.release.Load())
+		}
+	default:
+		// let it go, let it go...
+	}
+}
+
+// Len returns the number of objects currently waiting in the pool.
+func (pool *Pool[T]) Len() int {
+	return len(pool.items)
+}
+
+// drain removes all of the objects from the pool, passing each to release if not nil.
+func (pool *Pool[T]) drain(release func(T)) {
+	for {
+		select {
+		case item := <-pool.items:
+			if release != nil {
+				release(item)
+			}
+		default:
+			return
+		}
+	}
+}
+
+// Close shuts the pool down: every object waiting in the pool is removed and passed
+// to release (if not nil), and later calls to Recycle do nothing. New keeps working
+// after Close but always constructs a new object. It is safe to call more than once.
+// An object recycled while Close runs may be passed to release from Recycle instead.
+func (pool *Pool[T]) Close(release func(T)) {
+	if !pool.release.CompareAndSwap(nil, &release) {
+		return
+	}
+	pool.closed.Store(true)
+	pool.drain(release)
+}
@@ -0,0 +1,150 @@
+/*
+   Copyright (c) 2020, btnmasher
+   All rights reserved.
+
+   Redistribution and use in source and binary forms, with or without modification, are permitted provided that
+   the following conditions are met:
+
+   1. Redistributions of source code must retain the above copyright notice, this list of conditions and the
+      following disclaimer.
+
+   2. Redistributions in binary form must reproduce the above copyright notice, this list of conditions and
+      the following disclaimer in the documentation and/or other materials provided with the distribution.
+
+   3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse or
+      promote products derived from this software without specific prior written permission.
+
+   THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED
+   WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+   PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR
+   ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+   TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+   HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+   NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+   POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package util
+
+import (
+	"expvar"
+	"sort"
+	"sync"
+)
+
+// StatsExporter is implemented by pools which can report their statistics as a flat
+// set of named counters, suitable for expvar, Prometheus collectors and the like.
+type StatsExporter interface {
+	ExportStats() map[string]int64
+}
+
+var (
+	_ StatsExporter = (*BufferPool)(nil)
+	_ StatsExporter = (*ShardedBufferPool)(nil)
+	_ StatsExporter = (*WorkerPool)(nil)
+	_ StatsExporter = (*StatsRegistry)(nil)
+)
+
+// ExportStats returns the counters of Stats along with the number of idle Buffers.
+func (pool *BufferPool) ExportStats() map[string]int64 {
+	stats := pool.Stats()
+	return map[string]int64{
+		"hits":      int64(stats.Hits),
+		"misses":    int64(stats.Misses),
+		"recycles":  int64(stats.Recycles),
+		"discards":  int64(stats.Discards),
+		"oversized": int64(stats.Oversized),
+		"trimmed":   int64(stats.Trimmed),
+		"idle":      int64(len(pool.Buffers)),
+	}
+}
+
+// ExportStats returns the number of idle Buffers in the pool.
+func (pool *ShardedBufferPool) ExportStats() map[string]int64 {
+	return map[string]int64{
+		"idle": int64(pool.Len()),
+	}
+}
+
+// ExportStats returns the number of pending and dropped tasks.
+func (pool *WorkerPool) ExportStats() map[string]int64 {
+	pool.pendingMu.Lock()
+	pending := pool.pending
+	pool.pendingMu.Unlock()
+
+	return map[string]int64{
+		"queued":  int64(len(pool.tasks)),
+		"pending": int64(pending),
+		"dropped": int64(pool.Dropped()),
+	}
+}
+
+// StatsRegistry collects StatsExporters under names, so the statistics of many
+// pools can be scraped together.
+type StatsRegistry struct {
+	mu        sync.RWMutex
+	exporters map[string]StatsExporter
+}
+
+// NewStatsRegistry initializes and returns a pointer to a new StatsRegistry instance.
+func NewStatsRegistry() *StatsRegistry {
+	return &StatsRegistry{
+		exporters: make(map[string]StatsExporter),
+	}
+}
+
+// Register adds an exporter under the given name, replacing any exporter
+// already registered under it.
+func (r *StatsRegistry) Register(name string, exporter StatsExporter) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.exporters[name] = exporter
+}
+
+// Unregister removes the exporter registered under the given name.
+func (r *StatsRegistry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.exporters, name)
+}
+
+// Names returns the sorted names of the registered exporters.
+func (r *StatsRegistry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.exporters))
+	for name := range r.exporters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// ExportStats returns the counters of every registered exporter, each keyed
+// by the exporter's name and the counter's name joined with a dot.
+func (r *StatsRegistry) ExportStats() map[string]int64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stats := make(map[string]int64)
+	for name, exporter := range r.exporters {
+		for key, value := range exporter.ExportStats() {
+			stats[name+"."+key] = value
+		}
+	}
+
+	return stats
+}
+
+// PublishStats publishes an exporter as an expvar variable with the given name,
+// whose value is the exporter's counters as a JSON object. Like expvar.Publish,
+// it panics if the name is already in use.
+func PublishStats(name string, exporter StatsExporter) {
+	expvar.Publish(name, expvar.Func(func() any {
+		return exporter.ExportStats()
+	}))
+}
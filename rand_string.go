@@ -0,0 +1,87 @@
+/*
+   Copyright (c) 2020, btnmasher
+   All rights reserved.
+
+   Redistribution and use in source and binary forms, with or without modification, are permitted provided that
+   the following conditions are met:
+
+   1. Redistributions of source code must retain the above copyright notice, this list of conditions and the
+      following disclaimer.
+
+   2. Redistributions in binary form must reproduce the above copyright notice, this list of conditions and
+      the following disclaimer in the documentation and/or other materials provided with the distribution.
+
+   3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse or
+      promote products derived from this software without specific prior written permission.
+
+   THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED
+   WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+   PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR
+   ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+   TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+   HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+   NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+   POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package util
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+)
+
+// Charset is the set of characters RandString picks from.
+type Charset string
+
+// Predefined character sets for RandString.
+const (
+	CharsetHex     Charset = "0123456789abcdef"
+	CharsetBase62  Charset = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	CharsetAlnum           = CharsetBase62 // The same letters and digits, by their more familiar name.
+	CharsetURLSafe Charset = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_"
+)
+
+// RandString returns a string of n characters picked uniformly at random from charset
+// using crypto/rand, suitable for tokens, nonces and temporary IDs. Random bytes which
+// would favour some characters over others are thrown away rather than wrapped around.
+// Returns an error wrapping ErrEmptyCharset if charset is empty, or the error of the
+// system's random source if it fails.
+func RandString(n int, charset Charset) (string, error) {
+	chars := []rune(charset)
+	if len(chars) == 0 {
+		return "", fmt.Errorf("RandString: Cannot pick characters, %w", ErrEmptyCharset)
+	}
+
+	out := make([]rune, 0, n)
+
+	if len(chars) > 256 {
+		limit := big.NewInt(int64(len(chars)))
+		for len(out) < n {
+			i, err := rand.Int(rand.Reader, limit)
+			if err != nil {
+				return "", err
+			}
+			out = append(out, chars[i.Int64()])
+		}
+		return string(out), nil
+	}
+
+	// Only bytes below the largest multiple of len(chars) map evenly onto chars.
+	limit := 256 - 256%len(chars)
+	buf := make([]byte, n+n/4+1)
+
+	for len(out) < n {
+		if _, err := rand.Read(buf); err != nil {
+			return "", err
+		}
+		for _, b := range buf {
+			if int(b) < limit && len(out) < n {
+				out = append(out, chars[int(b)%len(chars)])
+			}
+		}
+	}
+
+	return string(out), nil
+}
@@ -0,0 +1,63 @@
+/*
+   Copyright (c) 2020, btnmasher
+   All rights reserved.
+
+   Redistribution and use in source and binary forms, with or without modification, are permitted provided that
+   the following conditions are met:
+
+   1. Redistributions of source code must retain the above copyright notice, this list of conditions and the
+      following disclaimer.
+
+   2. Redistributions in binary form must reproduce the above copyright notice, this list of conditions and
+      the following disclaimer in the documentation and/or other materials provided with the distribution.
+
+   3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse or
+      promote products derived from this software without specific prior written permission.
+
+   THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED
+   WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+   PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR
+   ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+   TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+   HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+   NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+   POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package util
+
+// ReadOnlyMapString is a view of a ConcurrentMapString which only exposes the
+// methods that read from it, and never modifies it, even where the map has a default
+// value factory. It reflects all changes made to the underlying map.
+type ReadOnlyMapString struct {
+	m *ConcurrentMapString
+}
+
+// ReadOnly returns a read-only view backed by the map, suitable for handing to
+// consumers which must not modify it.
+func (m *ConcurrentMapString) ReadOnly() ReadOnlyMapString {
+	return ReadOnlyMapString{m: m}
+}
+
+// ForEach will call the provided function for each entry in the underlying map.
+func (v ReadOnlyMapString) ForEach(do func(string, string)) {
+	v.m.ForEach(do)
+}
+
+// Length returns the length of the underlying map.
+func (v ReadOnlyMapString) Length() int {
+	return v.m.Length()
+}
+
+// Get is used to get a key/value from the underlying map.
+// Returns a KeyError wrapping ErrKeyNotFound if the key does not exist. Unlike
+// ConcurrentMapString.Get, no default value is stored for a missing key.
+func (v ReadOnlyMapString) Get(key string) (string, error) {
+	return v.m.get(key, false)
+}
+
+// Exists is used by external callers to check if a value
+// exists in the underlying map and returns a boolean with the result.
+func (v ReadOnlyMapString) Exists(key string) bool {
+	return v.m.Exists(key)
+}
@@ -0,0 +1,79 @@
+/*
+   Copyright (c) 2020, btnmasher
+   All rights reserved.
+
+   Redistribution and use in source and binary forms, with or without modification, are permitted provided that
+   the following conditions are met:
+
+   1. Redistributions of source code must retain the above copyright notice, this list of conditions and the
+      following disclaimer.
+
+   2. Redistributions in binary form must reproduce the above copyright notice, this list of conditions and
+      the following disclaimer in the documentation and/or other materials provided with the distribution.
+
+   3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse or
+      promote products derived from this software without specific prior written permission.
+
+   THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED
+   WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+   PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR
+   ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+   TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+   HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+   NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+   POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package util
+
+import (
+	"bytes"
+	"sync/atomic"
+)
+
+// RefBuffer is a reference-counted Buffer taken from a pool, for sharing one Buffer
+// between several consumers, such as fanning a message out to many writers. Each
+// consumer calls Release when done with it, and the Buffer is recycled once the last
+// reference is released.
+type RefBuffer struct {
+	*bytes.Buffer
+	pool BufferSource
+	refs atomic.Int32
+}
+
+// NewRefBuffer takes a Buffer from the pool, wrapped with a single reference held by the caller.
+func NewRefBuffer(pool BufferSource) *RefBuffer {
+	rb := &RefBuffer{
+		Buffer: pool.New(),
+		pool:   pool,
+	}
+	rb.refs.Store(1)
+	return rb
+}
+
+// Retain adds a reference to the Buffer, to be given to another consumer,
+// and returns the RefBuffer for convenience.
+func (rb *RefBuffer) Retain() *RefBuffer {
+	if rb.refs.Add(1) <= 1 {
+		panic("RefBuffer: Retain called after the buffer was recycled")
+	}
+	return rb
+}
+
+// Release drops a reference to the Buffer, recycling it if this was the last one.
+// The Buffer must not be used by the caller afterwards.
+func (rb *RefBuffer) Release() {
+	switch refs := rb.refs.Add(-1); {
+	case refs == 0:
+		buf := rb.Buffer
+		rb.Buffer = nil
+		rb.pool.Recycle(buf)
+	case refs < 0:
+		panic("RefBuffer: Release called more times than Retain")
+	}
+}
+
+// Refs returns the current number of references to the Buffer.
+func (rb *RefBuffer) Refs() int {
+	return int(rb.refs.Load())
+}
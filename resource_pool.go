@@ -0,0 +1,252 @@
+/*
+   Copyright (c) 2020, btnmasher
+   All rights reserved.
+
+   Redistribution and use in source and binary forms, with or without modification, are permitted provided that
+   the following conditions are met:
+
+   1. Redistributions of source code must retain the above copyright notice, this list of conditions and the
+      following disclaimer.
+
+   2. Redistributions in binary form must reproduce the above copyright notice, this list of conditions and
+      the following disclaimer in the documentation and/or other materials provided with the distribution.
+
+   3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse or
+      promote products derived from this software without specific prior written permission.
+
+   THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED
+   WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+   PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR
+   ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+   TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+   HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+   NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+   POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package util
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ResourcePoolConfig describes how a ResourcePool creates, checks and disposes
+// of its resources, and how many of them it keeps.
+type ResourcePoolConfig[T any] struct {
+	// New creates a resource. It is required.
+	New func(ctx context.Context) (T, error)
+
+	// Validate, if not nil, is run on an idle resource as it is checked out. Resources
+	// which fail validation are closed and another one is tried.
+	Validate func(T) error
+
+	// Close, if not nil, releases a resource which is leaving the pool.
+	Close func(T) error
+
+	// MaxIdle is the number of resources kept waiting for reuse.
+	MaxIdle int
+
+	// MaxOpen limits the number of resources open at once, idle or checked out.
+	// Acquire waits for a resource to be released when the limit is reached.
+	// Zero means there is no limit.
+	MaxOpen int
+
+	// MaxLifetime is how long a resource may be used for after it was created, after
+	// which it is closed instead of being reused. Zero means there is no limit.
+	MaxLifetime time.Duration
+}
+
+// ResourcePool manages a set of expensive resources such as network connections,
+// handing them out for exclusive use and keeping them for reuse once released.
+type ResourcePool[T any] struct {
+	config ResourcePoolConfig[T]
+	idle   chan *Resource[T]
+	open   chan struct{} // Holds a token for each open resource when MaxOpen is set.
+	done   chan struct{} // Closed by Close.
+	once   sync.Once
+}
+
+// Resource is a resource checked out of a ResourcePool. It must be given back
+// with exactly one call to Release or Discard.
+type Resource[T any] struct {
+	value    T
+	created  time.Time
+	pool     *ResourcePool[T]
+	returned atomic.Bool
+}
+
+// NewResourcePool creates a new ResourcePool with the given configuration.
+func NewResourcePool[T any](config ResourcePoolConfig[T]) *ResourcePool[T] {
+	pool := &ResourcePool[T]{
+		config: config,
+		idle:   make(chan *Resource[T], max(config.MaxIdle, 0)),
+		done:   make(chan struct{}),
+	}
+
+	if config.MaxOpen > 0 {
+		pool.open = make(chan struct{}, config.MaxOpen)
+	}
+
+	return pool
+}
+
+// closed reports whether Close has been called.
+func (pool *ResourcePool[T]) closed() bool {
+	select {
+	case <-pool.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// expired reports whether the resource has outlived MaxLifetime.
+func (pool *ResourcePool[T]) expired(r *Resource[T]) bool {
+	return pool.config.MaxLifetime > 0 && time.Since(r.created) >= pool.config.MaxLifetime
+}
+
+// create makes a new resource, for which an open slot must already be held.
+func (pool *ResourcePool[T]) create(ctx context.Context) (*Resource[T], error) {
+	value, err := pool.config.New(ctx)
+	if err != nil {
+		pool.releaseSlot()
+		return nil, err
+	}
+
+	return &Resource[T]{value: value, created: time.Now(), pool: pool}, nil
+}
+
+// destroy closes a resource which is leaving the pool and frees its open slot.
+func (pool *ResourcePool[T]) destroy(r *Resource[T]) error {
+	defer pool.releaseSlot()
+
+	if pool.config.Close != nil {
+		return pool.config.Close(r.value)
+	}
+	return nil
+}
+
+// releaseSlot frees the open slot held by a resource.
+func (pool *ResourcePool[T]) releaseSlot() {
+	if pool.open != nil {
+		<-pool.open
+	}
+}
+
+// usable checks an idle resource before it is handed out, closing it if it has
+// expired or fails validation.
+func (pool *ResourcePool[T]) usable(r *Resource[T]) bool {
+	if pool.expired(r) || (pool.config.Validate != nil && pool.config.Validate(r.value) != nil) {
+		pool.destroy(r)
+		return false
+	}
+
+	r.returned.Store(false)
+	return true
+}
+
+// Acquire checks a resource out of the pool, reusing an idle one if possible and
+// otherwise creating a new one. If MaxOpen resources are already open, it waits for
+// one to be released. Returns ErrPoolClosed if the pool is closed, the context's
+// error if it is done first, or the error from creating a resource.
+func (pool *ResourcePool[T]) Acquire(ctx context.Context) (*Resource[T], error) {
+	for {
+		if pool.closed() {
+			return nil, ErrPoolClosed
+		}
+
+		select {
+		case r := <-pool.idle:
+			if pool.usable(r) {
+				return r, nil
+			}
+			continue
+		default:
+		}
+
+		if pool.open == nil {
+			return pool.create(ctx)
+		}
+
+		select {
+		case r := <-pool.idle:
+			if pool.usable(r) {
+				return r, nil
+			}
+		case pool.open <- struct{}{}:
+			return pool.create(ctx)
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-pool.done:
+			return nil, ErrPoolClosed
+		}
+	}
+}
+
+// Value returns the underlying resource.
+func (r *Resource[T]) Value() T {
+	return r.value
+}
+
+// Release gives the resource back to the pool for reuse. It is closed instead if it
+// has expired, the pool already holds MaxIdle idle resources, or the pool is closed.
+func (r *Resource[T]) Release() {
+	if r.returned.Swap(true) {
+		return
+	}
+
+	pool := r.pool
+
+	if pool.closed() || pool.expired(r) {
+		pool.destroy(r)
+		return
+	}
+
+	select {
+	case pool.idle <- r:
+		// Close may have drained the idle resources just before this one was added.
+		if pool.closed() {
+			pool.drain()
+		}
+	default:
+		pool.destroy(r)
+	}
+}
+
+// Discard closes the resource rather than reusing it, for example because it is
+// known to be broken. Returns the error from closing it.
+func (r *Resource[T]) Discard() error {
+	if r.returned.Swap(true) {
+		return nil
+	}
+	return r.pool.destroy(r)
+}
+
+// drain closes all of the idle resources.
+func (pool *ResourcePool[T]) drain() error {
+	var errs []error
+	for {
+		select {
+		case r := <-pool.idle:
+			if err := pool.destroy(r); err != nil {
+				errs = append(errs, err)
+			}
+		default:
+			return errors.Join(errs...)
+		}
+	}
+}
+
+// Close closes the pool and all of its idle resources, returning any errors from
+// closing them. Resources still checked out are closed as they are released.
+// Acquire returns ErrPoolClosed once the pool is closed.
+func (pool *ResourcePool[T]) Close() error {
+	pool.once.Do(func() {
+		close(pool.done)
+	})
+	return pool.drain()
+}
@@ -0,0 +1,105 @@
+/*
+   Copyright (c) 2020, btnmasher
+   All rights reserved.
+
+   Redistribution and use in source and binary forms, with or without modification, are permitted provided that
+   the following conditions are met:
+
+   1. Redistributions of source code must retain the above copyright notice, this list of conditions and the
+      following disclaimer.
+
+   2. Redistributions in binary form must reproduce the above copyright notice, this list of conditions and
+      the following disclaimer in the documentation and/or other materials provided with the distribution.
+
+   3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse or
+      promote products derived from this software without specific prior written permission.
+
+   THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED
+   WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+   PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR
+   ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+   TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+   HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+   NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+   POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package util
+
+import "unicode"
+
+// Reverse returns s with its runes in reverse order. Combining characters and
+// multi-rune emoji are torn apart; use ReverseGraphemes to keep them whole.
+func Reverse(s string) string {
+	runes := []rune(s)
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+	return string(runes)
+}
+
+// ReverseGraphemes returns s with its Graphemes in reverse order, so accented
+// letters and emoji survive intact.
+func ReverseGraphemes(s string) string {
+	clusters := Graphemes(s)
+
+	out := make([]byte, 0, len(s))
+	for i := len(clusters) - 1; i >= 0; i-- {
+		out = append(out, clusters[i]...)
+	}
+	return string(out)
+}
+
+// Graphemes splits s into the user-perceived characters it is made of. This is an
+// approximation of Unicode grapheme clusters which covers the common cases: a rune
+// is kept with any combining marks, variation selectors and emoji skin tone modifiers
+// that follow it, runes joined by a zero width joiner stay together, as do pairs of
+// regional indicators forming a flag, and "\r\n".
+func Graphemes(s string) []string {
+	var clusters []string
+	start := 0
+	prev := rune(-1)
+	regional := 0 // Regional indicators in a row, to pair them into flags.
+
+	for i, r := range s {
+		if i > start && !extendsCluster(prev, r, regional) {
+			clusters = append(clusters, s[start:i])
+			start = i
+			regional = 0
+		}
+
+		if isRegionalIndicator(r) {
+			regional++
+		}
+		prev = r
+	}
+
+	if start < len(s) {
+		clusters = append(clusters, s[start:])
+	}
+
+	return clusters
+}
+
+// extendsCluster reports whether r belongs to the same grapheme as the rune before it.
+func extendsCluster(prev, r rune, regional int) bool {
+	switch {
+	case prev == '\r' && r == '\n':
+		return true
+	case prev == '\u200d': // Zero width joiner.
+		return true
+	case r == '\u200d',
+		unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc),
+		r >= 0xfe00 && r <= 0xfe0f,   // Variation selectors.
+		r >= 0x1f3fb && r <= 0x1f3ff: // Emoji skin tone modifiers.
+		return true
+	case isRegionalIndicator(r) && isRegionalIndicator(prev):
+		return regional%2 == 1
+	}
+	return false
+}
+
+// isRegionalIndicator reports whether r is one of the letters that make up flag emoji.
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1f1e6 && r <= 0x1f1ff
+}
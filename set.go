@@ -0,0 +1,161 @@
+/*
+   Copyright (c) 2020, btnmasher
+   All rights reserved.
+
+   Redistribution and use in source and binary forms, with or without modification, are permitted provided that
+   the following conditions are met:
+
+   1. Redistributions of source code must retain the above copyright notice, this list of conditions and the
+      following disclaimer.
+
+   2. Redistributions in binary form must reproduce the above copyright notice, this list of conditions and
+      the following disclaimer in the documentation and/or other materials provided with the distribution.
+
+   3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse or
+      promote products derived from this software without specific prior written permission.
+
+   THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED
+   WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+   PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR
+   ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+   TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+   HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+   NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+   POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package util
+
+import "sync"
+
+// ConcurrentSet is a set of comparable values wrapped with a concurrent-safe API.
+type ConcurrentSet[T comparable] struct {
+	data map[T]struct{}
+	sync.RWMutex
+}
+
+// NewConcurrentSet initializes and returns a pointer to a new ConcurrentSet
+// instance holding the given items.
+func NewConcurrentSet[T comparable](items ...T) *ConcurrentSet[T] {
+	s := &ConcurrentSet[T]{
+		data: make(map[T]struct{}, len(items)),
+	}
+
+	for _, item := range items {
+		s.data[item] = struct{}{}
+	}
+
+	return s
+}
+
+// Add is used to add an item to the set.
+// Returns true if the item was not already in the set.
+func (s *ConcurrentSet[T]) Add(item T) bool {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, exists := s.data[item]; exists {
+		return false
+	}
+
+	s.data[item] = struct{}{}
+	return true
+}
+
+// Remove is used to remove an item from the set.
+// Returns true if the item was in the set.
+func (s *ConcurrentSet[T]) Remove(item T) bool {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, exists := s.data[item]; !exists {
+		return false
+	}
+
+	delete(s.data, item)
+	return true
+}
+
+// Contains is used to check if an item is in the set.
+func (s *ConcurrentSet[T]) Contains(item T) bool {
+	s.RLock()
+	defer s.RUnlock()
+
+	_, exists := s.data[item]
+	return exists
+}
+
+// Len returns the number of items in the set.
+func (s *ConcurrentSet[T]) Len() int {
+	s.RLock()
+	defer s.RUnlock()
+
+	return len(s.data)
+}
+
+// ForEach will call the provided function for each item in the set.
+func (s *ConcurrentSet[T]) ForEach(do func(T)) {
+	s.RLock()
+	defer s.RUnlock()
+
+	for item := range s.data {
+		do(item)
+	}
+}
+
+// ToSlice returns the items of the set in no particular order.
+func (s *ConcurrentSet[T]) ToSlice() []T {
+	s.RLock()
+	defer s.RUnlock()
+
+	items := make([]T, 0, len(s.data))
+	for item := range s.data {
+		items = append(items, item)
+	}
+
+	return items
+}
+
+// Union returns a new set holding the items which are in either set.
+func (s *ConcurrentSet[T]) Union(other *ConcurrentSet[T]) *ConcurrentSet[T] {
+	// Copy other out first so the two locks are never held at once.
+	result := NewConcurrentSet(other.ToSlice()...)
+
+	s.RLock()
+	defer s.RUnlock()
+
+	for item := range s.data {
+		result.data[item] = struct{}{}
+	}
+
+	return result
+}
+
+// Intersect returns a new set holding the items which are in both sets.
+func (s *ConcurrentSet[T]) Intersect(other *ConcurrentSet[T]) *ConcurrentSet[T] {
+	items := other.ToSlice()
+	result := NewConcurrentSet[T]()
+
+	s.RLock()
+	defer s.RUnlock()
+
+	for _, item := range items {
+		if _, exists := s.data[item]; exists {
+			result.data[item] = struct{}{}
+		}
+	}
+
+	return result
+}
+
+// Difference returns a new set holding the items which are in this set but not in other.
+func (s *ConcurrentSet[T]) Difference(other *ConcurrentSet[T]) *ConcurrentSet[T] {
+	items := other.ToSlice()
+
+	result := NewConcurrentSet(s.ToSlice()...)
+	for _, item := range items {
+		delete(result.data, item)
+	}
+
+	return result
+}
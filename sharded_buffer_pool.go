@@ -0,0 +1,193 @@
+/*
+   Copyright (c) 2020, btnmasher
+   All rights reserved.
+
+   Redistribution and use in source and binary forms, with or without modification, are permitted provided that
+   the following conditions are met:
+
+   1. Redistributions of source code must retain the above copyright notice, this list of conditions and the
+      following disclaimer.
+
+   2. Redistributions in binary form must reproduce the above copyright notice, this list of conditions and
+      the following disclaimer in the documentation and/or other materials provided with the distribution.
+
+   3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse or
+      promote products derived from this software without specific prior written permission.
+
+   THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED
+   WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+   PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR
+   ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+   TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+   HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+   NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+   POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package util
+
+import (
+	"bytes"
+	"math/rand/v2"
+	"runtime"
+	"sync/atomic"
+)
+
+// ShardedBufferPool spreads its Buffers over several channels, by default one per CPU,
+// so that goroutines on many cores don't all contend on a single channel. Each call
+// starts at a randomly chosen shard and steals from, or spills into, the others
+// before falling back to allocating or dropping a Buffer.
+type ShardedBufferPool struct {
+	shards []chan *bytes.Buffer
+
+	// MaxCapacity is the largest capacity of a Buffer which will be kept by Recycle.
+	// Zero means there is no limit.
+	MaxCapacity int
+
+	release atomic.Pointer[func(*bytes.Buffer)] // Passed to Close, stored before closed is set.
+	closed  atomic.Bool
+}
+
+var _ BufferSource = (*ShardedBufferPool)(nil)
+
+// NewShardedBufferPool creates a new ShardedBufferPool holding at most max Buffers,
+// divided between the given number of shards. If shards is not positive, one shard
+// per CPU is used. There are never more shards than Buffers, and a max of zero or
+// less makes a single shard which holds none, so every Buffer is allocated.
+func NewShardedBufferPool(max, shards int) *ShardedBufferPool {
+	if shards <= 0 {
+		shards = runtime.NumCPU()
+	}
+	if max <= 0 {
+		max, shards = 0, 1
+	} else {
+		shards = min(shards, max)
+	}
+
+	pool := &ShardedBufferPool{
+		shards: make([]chan *bytes.Buffer, shards),
+	}
+
+	// The first max%shards shards hold one more, so the capacities add up to max.
+	for i := range pool.shards {
+		per := max / shards
+		if i < max%shards {
+			per++
+		}
+		pool.shards[i] = make(chan *bytes.Buffer, per)
+	}
+
+	return pool
+}
+
+// Warmup fills the pool with the specified number of Buffers, each pre-allocated
+// to hold length bytes, spread evenly across the shards. A Buffer for a full shard
+// goes to the next shard with room, and warmup stops once every shard is full.
+func (pool *ShardedBufferPool) Warmup(num, length int) {
+	for i := 0; i < num; i++ {
+		if pool.closed.Load() || pool.Len() == pool.capacity() {
+			return // Don't allocate a buffer with nowhere to put it.
+		}
+
+		buf := &bytes.Buffer{}
+		buf.Grow(length)
+
+		if !pool.put(buf, i%len(pool.shards)) {
+			return
+		}
+	}
+}
+
+// put adds a Buffer to the first shard with room, starting at shard start.
+// Returns false if every shard is full.
+func (pool *ShardedBufferPool) put(buf *bytes.Buffer, start int) bool {
+	for i := range pool.shards {
+		select {
+		case pool.shards[(start+i)%len(pool.shards)] <- buf:
+			// Close may have drained the pool just before this Buffer was added.
+			if pool.closed.Load() {
+				pool.drain(*pool.release.Load())
+			}
+			return true
+		default:
+		}
+	}
+	return false
+}
+
+// capacity returns the most Buffers the pool can hold.
+func (pool *ShardedBufferPool) capacity() int {
+	total := 0
+	for _, shard := range pool.shards {
+		total += cap(shard)
+	}
+	return total
+}
+
+// New takes a Buffer from the pool, allocating one if every shard is empty.
+func (pool *ShardedBufferPool) New() *bytes.Buffer {
+	start := rand.IntN(len(pool.shards))
+
+	for i := range pool.shards {
+		select {
+		case buf := <-pool.shards[(start+i)%len(pool.shards)]:
+			return buf
+		default:
+		}
+	}
+
+	return &bytes.Buffer{}
+}
+
+// Recycle returns a Buffer to the pool, dropping it if every shard is full.
+// Buffers which have grown beyond MaxCapacity are dropped instead, as are all
+// Buffers once the pool is closed.
+func (pool *ShardedBufferPool) Recycle(buf *bytes.Buffer) {
+	if pool.closed.Load() {
+		return
+	}
+	if pool.MaxCapacity > 0 && buf.Cap() > pool.MaxCapacity {
+		return
+	}
+
+	buf.Reset()
+	pool.put(buf, rand.IntN(len(pool.shards)))
+}
+
+// Len returns the number of Buffers currently waiting in the pool.
+func (pool *ShardedBufferPool) Len() int {
+	total := 0
+	for _, shard := range pool.shards {
+		total += len(shard)
+	}
+	return total
+}
+
+// drain removes all of the Buffers from every shard, passing each to release if not nil.
+func (pool *ShardedBufferPool) drain(release func(*bytes.Buffer)) {
+	for _, shard := range pool.shards {
+	empty:
+		for {
+			select {
+			case buf := <-shard:
+				if release != nil {
+					release(buf)
+				}
+			default:
+				break empty
+			}
+		}
+	}
+}
+
+// Close shuts the pool down like BufferPool.Close: every Buffer waiting in the shards
+// is removed and passed to release (if not nil), and later calls to Recycle do nothing.
+// New keeps working after Close but always allocates. It is safe to call more than
+// once. A Buffer recycled while Close runs may be passed to release from Recycle instead.
+func (pool *ShardedBufferPool) Close(release func(*bytes.Buffer)) {
+	if !pool.release.CompareAndSwap(nil, &release) {
+		return
+	}
+	pool.closed.Store(true)
+	pool.drain(release)
+}
@@ -0,0 +1,451 @@
+/*
+   Copyright (c) 2020, btnmasher
+   All rights reserved.
+
+   Redistribution and use in source and binary forms, with or without modification, are permitted provided that
+   the following conditions are met:
+
+   1. Redistributions of source code must retain the above copyright notice, this list of conditions and the
+      following disclaimer.
+
+   2. Redistributions in binary form must reproduce the above copyright notice, this list of conditions and
+      the following disclaimer in the documentation and/or other materials provided with the distribution.
+
+   3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse or
+      promote products derived from this software without specific prior written permission.
+
+   THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED
+   WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+   PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR
+   ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+   TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+   HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+   NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+   POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package util
+
+import (
+	"encoding/json"
+	"io"
+	"iter"
+)
+
+// DefaultShardCount is the number of shards used by NewShardedConcurrentMapString
+// when a non-positive shard count is requested.
+const DefaultShardCount = 32
+
+// ShardedConcurrentMapString spreads its entries over a number of ConcurrentMapString
+// shards selected by a hash of the key, so that writers to different keys do not
+// serialize on a single lock. It exposes the same API as ConcurrentMapString.
+type ShardedConcurrentMapString struct {
+	shards []*ConcurrentMapString
+	mask   uint32
+}
+
+// NewShardedConcurrentMapString initializes and returns a pointer to a new
+// ShardedConcurrentMapString instance. The shard count is rounded up to the
+// next power of two, or DefaultShardCount is used if shards is not positive.
+func NewShardedConcurrentMapString(shards int) *ShardedConcurrentMapString {
+	if shards <= 0 {
+		shards = DefaultShardCount
+	}
+
+	count := powerOfTwo(shards)
+
+	m := &ShardedConcurrentMapString{
+		shards: make([]*ConcurrentMapString, count),
+		mask:   uint32(count - 1),
+	}
+
+	for i := range m.shards {
+		m.shards[i] = NewConcurrentMapString()
+	}
+
+	return m
+}
+
+// hashKey returns the 32-bit FNV-1a hash of the key, computed inline
+// so that hashing doesn't allocate.
+func hashKey(key string) uint32 {
+	hash := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		hash ^= uint32(key[i])
+		hash *= 16777619
+	}
+	return hash
+}
+
+// powerOfTwo returns the smallest power of two which is at least n.
+func powerOfTwo(n int) int {
+	count := 1
+	for count < n {
+		count <<= 1
+	}
+	return count
+}
+
+// shardIndex returns the index of the shard responsible for the given key.
+func (m *ShardedConcurrentMapString) shardIndex(key string) uint32 {
+	return hashKey(key) & m.mask
+}
+
+// shard returns the shard responsible for the given key.
+func (m *ShardedConcurrentMapString) shard(key string) *ConcurrentMapString {
+	return m.shards[m.shardIndex(key)]
+}
+
+// ShardCount returns the number of shards backing the map.
+func (m *ShardedConcurrentMapString) ShardCount() int {
+	return len(m.shards)
+}
+
+// ForEach will call the provided function for each entry in the ShardedConcurrentMapString.
+// Shards are visited one at a time, so the iteration is not a consistent snapshot
+// of the whole map if it is being written to concurrently.
+func (m *ShardedConcurrentMapString) ForEach(do func(string, string)) {
+	for _, shard := range m.shards {
+		shard.ForEach(do)
+	}
+}
+
+// ForEachUntil will call the provided function for each entry in the ShardedConcurrentMapString
+// until it returns true, at which point the iteration stops early.
+// Returns true if the iteration was stopped by the provided function.
+func (m *ShardedConcurrentMapString) ForEachUntil(do func(string, string) bool) bool {
+	for _, shard := range m.shards {
+		if shard.ForEachUntil(do) {
+			return true
+		}
+	}
+	return false
+}
+
+// ForEachSnapshot will call the provided function for each entry in the ShardedConcurrentMapString
+// without holding any lock, copying each shard's entries in turn as the iteration reaches it.
+func (m *ShardedConcurrentMapString) ForEachSnapshot(do func(string, string)) {
+	for _, shard := range m.shards {
+		shard.ForEachSnapshot(do)
+	}
+}
+
+// Length returns the combined length of all of the shards.
+func (m *ShardedConcurrentMapString) Length() int {
+	total := 0
+	for _, shard := range m.shards {
+		total += shard.Length()
+	}
+	return total
+}
+
+// Add is used to add a key/value to the map.
+// Returns an error if the key already exists.
+func (m *ShardedConcurrentMapString) Add(key string, value string) error {
+	return m.shard(key).Add(key, value)
+}
+
+// Del is used to remove a key/value from the map.
+// Returns an error if the key does not exist.
+func (m *ShardedConcurrentMapString) Del(key string) error {
+	return m.shard(key).Del(key)
+}
+
+// Get is used to get a key/value from the map.
+// Returns an error if the key does not exist.
+func (m *ShardedConcurrentMapString) Get(key string) (string, error) {
+	return m.shard(key).Get(key)
+}
+
+// Set is used to change an existing key/value in the map.
+// Returns an error if the key does not exist.
+func (m *ShardedConcurrentMapString) Set(key string, value string) error {
+	return m.shard(key).Set(key, value)
+}
+
+// Exists is used by external callers to check if a value
+// exists in the map and returns a boolean with the result.
+func (m *ShardedConcurrentMapString) Exists(key string) bool {
+	return m.shard(key).Exists(key)
+}
+
+// GetOrSet is used to get the value of a key from the map, adding the given value
+// if the key does not exist yet. The check and the write happen under a single lock.
+// Returns the value now in the map, and true if it was already present.
+func (m *ShardedConcurrentMapString) GetOrSet(key string, value string) (string, bool) {
+	return m.shard(key).GetOrSet(key, value)
+}
+
+// Upsert is used to add or change a key/value in the map based on its current state.
+// See ConcurrentMapString.Upsert for details.
+func (m *ShardedConcurrentMapString) Upsert(key string, update func(old string, exists bool) string) string {
+	return m.shard(key).Upsert(key, update)
+}
+
+// CompareAndSwap is used to change the value of a key only if its current value is old.
+// Returns true if the swap was performed.
+func (m *ShardedConcurrentMapString) CompareAndSwap(key string, old string, new string) bool {
+	return m.shard(key).CompareAndSwap(key, old, new)
+}
+
+// CompareAndDelete is used to remove a key/value only if its current value is old.
+// Returns true if the entry was deleted.
+func (m *ShardedConcurrentMapString) CompareAndDelete(key string, old string) bool {
+	return m.shard(key).CompareAndDelete(key, old)
+}
+
+// Snapshot returns a copy of the entries of all of the shards. Each shard is
+// copied under its own read lock, one at a time.
+func (m *ShardedConcurrentMapString) Snapshot() map[string]string {
+	snap := make(map[string]string)
+	m.ForEach(func(key, val string) {
+		snap[key] = val
+	})
+	return snap
+}
+
+// Clone returns a new ShardedConcurrentMapString with the same shard count
+// holding a copy of the current entries.
+func (m *ShardedConcurrentMapString) Clone() *ShardedConcurrentMapString {
+	c := &ShardedConcurrentMapString{
+		shards: make([]*ConcurrentMapString, len(m.shards)),
+		mask:   m.mask,
+	}
+
+	for i, shard := range m.shards {
+		c.shards[i] = shard.Clone()
+	}
+
+	return c
+}
+
+// Keys returns a slice of all of the keys in all of the shards, in no particular order.
+func (m *ShardedConcurrentMapString) Keys() []string {
+	var keys []string
+	for _, shard := range m.shards {
+		keys = append(keys, shard.Keys()...)
+	}
+	return keys
+}
+
+// Values returns a slice of all of the values in all of the shards, in no particular order.
+func (m *ShardedConcurrentMapString) Values() []string {
+	var vals []string
+	for _, shard := range m.shards {
+		vals = append(vals, shard.Values()...)
+	}
+	return vals
+}
+
+// MarshalJSON implements json.Marshaler, encoding the entries of all of the
+// shards as a single JSON object.
+func (m *ShardedConcurrentMapString) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.Snapshot())
+}
+
+// UnmarshalJSON implements json.Unmarshaler, replacing the contents of the map
+// with the decoded JSON object, like ConcurrentMapString.UnmarshalJSON for each
+// shard. The map is left untouched if decoding fails. A zero value map is initialized with DefaultShardCount shards.
+func (m *ShardedConcurrentMapString) UnmarshalJSON(b []byte) error {
+	data := make(map[string]string)
+
+	if err := json.Unmarshal(b, &data); err != nil {
+		return err
+	}
+
+	if m.shards == nil {
+		*m = *NewShardedConcurrentMapString(DefaultShardCount)
+	}
+
+	split := make([]map[string]string, len(m.shards))
+	for i := range split {
+		split[i] = make(map[string]string)
+	}
+
+	for key, val := range data {
+		split[m.shardIndex(key)][key] = val
+	}
+
+	for i, shard := range m.shards {
+		shard.Lock()
+		shard.replace(split[i])
+		shard.Unlock()
+	}
+
+	return nil
+}
+
+// splitEntries groups the given entries by the index of the shard responsible for them.
+func (m *ShardedConcurrentMapString) splitEntries(entries map[string]string) map[uint32]map[string]string {
+	split := make(map[uint32]map[string]string)
+	for key, val := range entries {
+		idx := m.shardIndex(key)
+		if split[idx] == nil {
+			split[idx] = make(map[string]string)
+		}
+		split[idx][key] = val
+	}
+	return split
+}
+
+// splitKeys groups the given keys by the index of the shard responsible for them.
+func (m *ShardedConcurrentMapString) splitKeys(keys []string) map[uint32][]string {
+	split := make(map[uint32][]string)
+	for _, key := range keys {
+		idx := m.shardIndex(key)
+		split[idx] = append(split[idx], key)
+	}
+	return split
+}
+
+// mergeErrors copies the errors from src into dst, allocating dst if needed.
+func mergeErrors(dst, src map[string]error) map[string]error {
+	if len(src) == 0 {
+		return dst
+	}
+	if dst == nil {
+		dst = make(map[string]error, len(src))
+	}
+	for key, err := range src {
+		dst[key] = err
+	}
+	return dst
+}
+
+// AddMany is used to add several key/values to the map, acquiring each shard's lock once.
+// Returns the error for each key which could not be added, or nil if all of them were.
+func (m *ShardedConcurrentMapString) AddMany(entries map[string]string) map[string]error {
+	var errs map[string]error
+	for idx, group := range m.splitEntries(entries) {
+		errs = mergeErrors(errs, m.shards[idx].AddMany(group))
+	}
+	return errs
+}
+
+// SetMany is used to change several existing key/values in the map, acquiring each shard's
+// lock once. Returns the error for each key which could not be set, or nil if all of them were.
+func (m *ShardedConcurrentMapString) SetMany(entries map[string]string) map[string]error {
+	var errs map[string]error
+	for idx, group := range m.splitEntries(entries) {
+		errs = mergeErrors(errs, m.shards[idx].SetMany(group))
+	}
+	return errs
+}
+
+// DelMany is used to remove several key/values from the map, acquiring each shard's lock once.
+// Returns the error for each key which could not be deleted, or nil if all of them were.
+func (m *ShardedConcurrentMapString) DelMany(keys []string) map[string]error {
+	var errs map[string]error
+	for idx, group := range m.splitKeys(keys) {
+		errs = mergeErrors(errs, m.shards[idx].DelMany(group))
+	}
+	return errs
+}
+
+// GetMany is used to get several key/values from the map, acquiring each shard's lock once.
+// Returns a map holding each of the requested keys that exist; missing keys are omitted.
+func (m *ShardedConcurrentMapString) GetMany(keys []string) map[string]string {
+	found := make(map[string]string, len(keys))
+	for idx, group := range m.splitKeys(keys) {
+		for key, val := range m.shards[idx].GetMany(group) {
+			found[key] = val
+		}
+	}
+	return found
+}
+
+// All returns an iterator over the entries of the map for use with range.
+// Each shard is snapshotted in turn as the iteration reaches it, so the loop
+// body runs without any lock held and is free to modify the map.
+func (m *ShardedConcurrentMapString) All() iter.Seq2[string, string] {
+	return func(yield func(string, string) bool) {
+		for _, shard := range m.shards {
+			for key, val := range shard.Snapshot() {
+				if !yield(key, val) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// SaveTo writes the contents of the map to w as a JSON object.
+func (m *ShardedConcurrentMapString) SaveTo(w io.Writer) error {
+	return json.NewEncoder(w).Encode(m)
+}
+
+// LoadFrom replaces the contents of the map with the JSON object read from r,
+// as written by SaveTo. The map is left untouched if decoding fails.
+func (m *ShardedConcurrentMapString) LoadFrom(r io.Reader) error {
+	return json.NewDecoder(r).Decode(m)
+}
+
+// Clear is used to remove all of the key/values from the map, one shard at a time.
+func (m *ShardedConcurrentMapString) Clear() {
+	for _, shard := range m.shards {
+		shard.Clear()
+	}
+}
+
+// Pop is used to get a key/value from the map and remove it under a single lock.
+// Returns an error if the key does not exist.
+func (m *ShardedConcurrentMapString) Pop(key string) (string, error) {
+	return m.shard(key).Pop(key)
+}
+
+// GetAndDelete is used to get a key/value from the map and remove it under a single lock.
+// Returns the removed value and true, or false if the key did not exist.
+func (m *ShardedConcurrentMapString) GetAndDelete(key string) (string, bool) {
+	return m.shard(key).GetAndDelete(key)
+}
+
+// DeleteIf is used to remove every key/value for which the provided function returns true,
+// holding each shard's lock in turn. Returns the number of entries removed.
+func (m *ShardedConcurrentMapString) DeleteIf(pred func(string, string) bool) int {
+	count := 0
+	for _, shard := range m.shards {
+		count += shard.DeleteIf(pred)
+	}
+	return count
+}
+
+// ShardStats describes the load on a single shard of a ShardedConcurrentMapString.
+type ShardStats struct {
+	Length    int    // Number of entries held by the shard.
+	Contended uint64 // Number of lock acquisitions which had to wait.
+}
+
+// ShardStats returns the current load on each of the shards, indexed by shard.
+// A shard with far more entries or contention than the others indicates hot keys
+// or a shard count too low for the workload.
+func (m *ShardedConcurrentMapString) ShardStats() []ShardStats {
+	stats := make([]ShardStats, len(m.shards))
+	for i, shard := range m.shards {
+		stats[i] = ShardStats{
+			Length:    shard.Length(),
+			Contended: shard.Contention(),
+		}
+	}
+	return stats
+}
+
+// LoadFactor returns the length of the fullest shard relative to the mean shard
+// length. A value of 1 means the entries are spread perfectly evenly, while larger
+// values indicate skew. Returns zero if the map is empty.
+func (m *ShardedConcurrentMapString) LoadFactor() float64 {
+	total, fullest := 0, 0
+	for _, shard := range m.shards {
+		n := shard.Length()
+		total += n
+		if n > fullest {
+			fullest = n
+		}
+	}
+
+	if total == 0 {
+		return 0
+	}
+
+	mean := float64(total) / float64(len(m.shards))
+	return float64(fullest) / mean
+}
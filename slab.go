@@ -0,0 +1,105 @@
+/*
+   Copyright (c) 2020, btnmasher
+   All rights reserved.
+
+   Redistribution and use in source and binary forms, with or without modification, are permitted provided that
+   the following conditions are met:
+
+   1. Redistributions of source code must retain the above copyright notice, this list of conditions and the
+      following disclaimer.
+
+   2. Redistributions in binary form must reproduce the above copyright notice, this list of conditions and
+      the following disclaimer in the documentation and/or other materials provided with the distribution.
+
+   3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse or
+      promote products derived from this software without specific prior written permission.
+
+   THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED
+   WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+   PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR
+   ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+   TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+   HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+   NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+   POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package util
+
+import "sync"
+
+// Slab allocates values of type T from pre-allocated slabs and recycles them through
+// a free list, for objects created and discarded at a high rate such as protocol
+// messages. Slabs are never released, so memory stays at the peak number of values
+// in use at once.
+type Slab[T any] struct {
+	slabSize int
+	free     []*T
+	slabs    int
+	mu       sync.Mutex
+}
+
+// NewSlab creates a new Slab which allocates slabSize values at a time.
+func NewSlab[T any](slabSize int) *Slab[T] {
+	if slabSize < 1 {
+		slabSize = 1
+	}
+
+	return &Slab[T]{
+		slabSize: slabSize,
+	}
+}
+
+// grow allocates a new slab and adds its values to the free list.
+// Must be called with the lock held.
+func (s *Slab[T]) grow() {
+	slab := make([]T, s.slabSize)
+	for i := range slab {
+		s.free = append(s.free, &slab[i])
+	}
+	s.slabs++
+}
+
+// Alloc returns a pointer to a zeroed value, allocating a new slab if none are free.
+func (s *Slab[T]) Alloc() *T {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.free) == 0 {
+		s.grow()
+	}
+
+	last := len(s.free) - 1
+	p := s.free[last]
+	s.free[last] = nil
+	s.free = s.free[:last]
+
+	return p
+}
+
+// Free zeroes a value obtained from Alloc and returns it to the free list.
+// The value must not be used afterwards, and must not be freed twice.
+func (s *Slab[T]) Free(p *T) {
+	var zero T
+	*p = zero
+
+	s.mu.Lock()
+	s.free = append(s.free, p)
+	s.mu.Unlock()
+}
+
+// Available returns the number of values on the free list.
+func (s *Slab[T]) Available() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.free)
+}
+
+// Capacity returns the total number of values in all of the slabs allocated so far.
+func (s *Slab[T]) Capacity() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.slabs * s.slabSize
+}
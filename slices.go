@@ -0,0 +1,92 @@
+/*
+   Copyright (c) 2020, btnmasher
+   All rights reserved.
+
+   Redistribution and use in source and binary forms, with or without modification, are permitted provided that
+   the following conditions are met:
+
+   1. Redistributions of source code must retain the above copyright notice, this list of conditions and the
+      following disclaimer.
+
+   2. Redistributions in binary form must reproduce the above copyright notice, this list of conditions and
+      the following disclaimer in the documentation and/or other materials provided with the distribution.
+
+   3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse or
+      promote products derived from this software without specific prior written permission.
+
+   THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED
+   WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+   PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR
+   ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+   TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+   HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+   NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+   POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package util
+
+import "slices"
+
+// Contains reports whether v is in list.
+func Contains[T comparable](list []T, v T) bool {
+	return slices.Contains(list, v)
+}
+
+// IndexOf returns the index of the first occurrence of v in list, or -1 if it isn't there.
+func IndexOf[T comparable](list []T, v T) int {
+	return slices.Index(list, v)
+}
+
+// Unique returns the distinct elements of list, in the order they first appear.
+func Unique[T comparable](list []T) []T {
+	seen := make(map[T]struct{}, len(list))
+	unique := make([]T, 0, len(list))
+
+	for _, v := range list {
+		if _, ok := seen[v]; !ok {
+			seen[v] = struct{}{}
+			unique = append(unique, v)
+		}
+	}
+
+	return unique
+}
+
+// Difference returns the distinct elements of a which are not in b,
+// in the order they first appear in a.
+func Difference[T comparable](a, b []T) []T {
+	exclude := make(map[T]struct{}, len(b))
+	for _, v := range b {
+		exclude[v] = struct{}{}
+	}
+
+	diff := []T{}
+	for _, v := range a {
+		if _, ok := exclude[v]; !ok {
+			exclude[v] = struct{}{} // Only take the first occurrence.
+			diff = append(diff, v)
+		}
+	}
+
+	return diff
+}
+
+// Intersection returns the distinct elements of a which are also in b,
+// in the order they first appear in a.
+func Intersection[T comparable](a, b []T) []T {
+	include := make(map[T]struct{}, len(b))
+	for _, v := range b {
+		include[v] = struct{}{}
+	}
+
+	common := []T{}
+	for _, v := range a {
+		if _, ok := include[v]; ok {
+			delete(include, v) // Only take the first occurrence.
+			common = append(common, v)
+		}
+	}
+
+	return common
+}
@@ -0,0 +1,79 @@
+/*
+   Copyright (c) 2020, btnmasher
+   All rights reserved.
+
+   Redistribution and use in source and binary forms, with or without modification, are permitted provided that
+   the following conditions are met:
+
+   1. Redistributions of source code must retain the above copyright notice, this list of conditions and the
+      following disclaimer.
+
+   2. Redistributions in binary form must reproduce the above copyright notice, this list of conditions and
+      the following disclaimer in the documentation and/or other materials provided with the distribution.
+
+   3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse or
+      promote products derived from this software without specific prior written permission.
+
+   THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED
+   WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+   PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR
+   ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+   TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+   HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+   NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+   POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package util
+
+import (
+	"strings"
+	"unicode"
+)
+
+// slugFolds transliterates the common accented and special Latin letters to ASCII.
+var slugFolds = func() map[rune]string {
+	folds := make(map[rune]string)
+	for from, to := range map[string]string{
+		"àáâãäåāăą": "a", "çćĉċč": "c", "ďđ": "d", "èéêëēĕėęě": "e", "ĝğġģ": "g",
+		"ĥħ": "h", "ìíîïĩīĭįı": "i", "ĵ": "j", "ķ": "k", "ĺļľŀł": "l", "ñńņňŉ": "n",
+		"òóôõöøōŏő": "o", "ŕŗř": "r", "śŝşš": "s", "ţťŧ": "t", "ùúûüũūŭůűų": "u",
+		"ŵ": "w", "ýÿŷ": "y", "źżž": "z",
+		"æ": "ae", "œ": "oe", "ß": "ss", "þ": "th", "ð": "d",
+	} {
+		for _, r := range from {
+			folds[r] = to
+		}
+	}
+	return folds
+}()
+
+// Slugify turns s into a lower case, URL and ID safe name: common accented letters
+// are transliterated to ASCII, every run of other characters which aren't ASCII
+// letters or digits becomes a single dash, and leading and trailing dashes are
+// trimmed, so "Héllo, Wörld!" becomes "hello-world".
+func Slugify(s string) string {
+	var out strings.Builder
+	dash := false // Whether a dash is owed before the next letter or digit.
+
+	write := func(part string) {
+		if dash && out.Len() > 0 {
+			out.WriteByte('-')
+		}
+		dash = false
+		out.WriteString(part)
+	}
+
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			write(string(r))
+		case slugFolds[r] != "":
+			write(slugFolds[r])
+		default:
+			dash = true
+		}
+	}
+
+	return out.String()
+}
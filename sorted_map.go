@@ -0,0 +1,342 @@
+/*
+   Copyright (c) 2020, btnmasher
+   All rights reserved.
+
+   Redistribution and use in source and binary forms, with or without modification, are permitted provided that
+   the following conditions are met:
+
+   1. Redistributions of source code must retain the above copyright notice, this list of conditions and the
+      following disclaimer.
+
+   2. Redistributions in binary form must reproduce the above copyright notice, this list of conditions and
+      the following disclaimer in the documentation and/or other materials provided with the distribution.
+
+   3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse or
+      promote products derived from this software without specific prior written permission.
+
+   THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED
+   WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+   PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR
+   ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+   TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+   HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+   NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+   POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package util
+
+import (
+	"cmp"
+	"fmt"
+	"iter"
+	"math/bits"
+	"math/rand/v2"
+	"sync"
+)
+
+// skipMaxLevel bounds the height of the skip list, which comfortably
+// covers 4^16 entries at the level probability used.
+const skipMaxLevel = 16
+
+// skipNode is an entry in the skip list backing ConcurrentSortedMap.
+type skipNode[K any, V any] struct {
+	key   K
+	value V
+	next  []*skipNode[K, V]
+}
+
+// ConcurrentSortedMap is a concurrent-safe ordered map backed by a skip list.
+// Iteration is always in ascending key order, and it supports nearest-key
+// lookups and range scans.
+type ConcurrentSortedMap[K any, V any] struct {
+	head   *skipNode[K, V]
+	level  int
+	length int
+	less   func(a, b K) bool
+	sync.RWMutex
+}
+
+// NewConcurrentSortedMap initializes and returns a pointer to a new ConcurrentSortedMap
+// instance ordered by the natural ordering of its keys.
+func NewConcurrentSortedMap[K cmp.Ordered, V any]() *ConcurrentSortedMap[K, V] {
+	return NewConcurrentSortedMapFunc[K, V](cmp.Less[K])
+}
+
+// NewConcurrentSortedMapFunc initializes and returns a pointer to a new ConcurrentSortedMap
+// instance ordered by less, which must be a strict weak ordering. Keys for which neither
+// is less than the other are treated as the same key, so a case-insensitive less gives
+// a map with case-insensitive keys.
+func NewConcurrentSortedMapFunc[K any, V any](less func(a, b K) bool) *ConcurrentSortedMap[K, V] {
+	return &ConcurrentSortedMap[K, V]{
+		head:  &skipNode[K, V]{next: make([]*skipNode[K, V], skipMaxLevel)},
+		level: 1,
+		less:  less,
+	}
+}
+
+// randomLevel picks the height of a new node, with each level a quarter as likely as the last.
+func randomLevel() int {
+	level := 1 + bits.TrailingZeros64(rand.Uint64())/2
+	if level > skipMaxLevel {
+		level = skipMaxLevel
+	}
+	return level
+}
+
+// seek fills update with the last node before key on each level and returns the
+// first node whose key is not less than key, or nil. Must be called with the lock held.
+func (m *ConcurrentSortedMap[K, V]) seek(key K, update *[skipMaxLevel]*skipNode[K, V]) *skipNode[K, V] {
+	x := m.head
+	for i := m.level - 1; i >= 0; i-- {
+		for x.next[i] != nil && m.less(x.next[i].key, key) {
+			x = x.next[i]
+		}
+		if update != nil {
+			update[i] = x
+		}
+	}
+	return x.next[0]
+}
+
+// find returns the node holding key, or nil. Must be called with the lock held.
+func (m *ConcurrentSortedMap[K, V]) find(key K) *skipNode[K, V] {
+	node := m.seek(key, nil)
+	if node == nil || m.less(key, node.key) {
+		return nil
+	}
+	return node
+}
+
+// ForEach will call the provided function for each entry in the ConcurrentSortedMap
+// in ascending key order.
+func (m *ConcurrentSortedMap[K, V]) ForEach(do func(K, V)) {
+	m.RLock()
+	defer m.RUnlock()
+
+	for x := m.head.next[0]; x != nil; x = x.next[0] {
+		do(x.key, x.value)
+	}
+}
+
+// Keys returns a slice of all of the keys currently in the map, in ascending order.
+func (m *ConcurrentSortedMap[K, V]) Keys() []K {
+	m.RLock()
+	defer m.RUnlock()
+
+	keys := make([]K, 0, m.length)
+	for x := m.head.next[0]; x != nil; x = x.next[0] {
+		keys = append(keys, x.key)
+	}
+
+	return keys
+}
+
+// Length returns the number of entries in the map.
+func (m *ConcurrentSortedMap[K, V]) Length() int {
+	m.RLock()
+	defer m.RUnlock()
+
+	return m.length
+}
+
+// Add is used to add a key/value to the map.
+// Returns an error if the key already exists.
+func (m *ConcurrentSortedMap[K, V]) Add(key K, value V) error {
+	m.Lock()
+	defer m.Unlock()
+
+	var update [skipMaxLevel]*skipNode[K, V]
+	node := m.seek(key, &update)
+
+	if node != nil && !m.less(key, node.key) {
+		return &KeyError{Op: "ConcurrentSortedMap: Cannot add map entry", Key: fmt.Sprint(key), Err: ErrKeyExists}
+	}
+
+	level := randomLevel()
+	for i := m.level; i < level; i++ {
+		update[i] = m.head
+	}
+	if level > m.level {
+		m.level = level
+	}
+
+	node = &skipNode[K, V]{key: key, value: value, next: make([]*skipNode[K, V], level)}
+	for i := 0; i < level; i++ {
+		node.next[i] = update[i].next[i]
+		update[i].next[i] = node
+	}
+
+	m.length++
+	return nil
+}
+
+// Del is used to remove a key/value from the map.
+// Returns an error if the key does not exist.
+func (m *ConcurrentSortedMap[K, V]) Del(key K) error {
+	m.Lock()
+	defer m.Unlock()
+
+	var update [skipMaxLevel]*skipNode[K, V]
+	node := m.seek(key, &update)
+
+	if node == nil || m.less(key, node.key) {
+		return &KeyError{Op: "ConcurrentSortedMap: Cannot delete map entry", Key: fmt.Sprint(key), Err: ErrKeyNotFound}
+	}
+
+	for i := range node.next {
+		update[i].next[i] = node.next[i]
+	}
+	for m.level > 1 && m.head.next[m.level-1] == nil {
+		m.level--
+	}
+
+	m.length--
+	return nil
+}
+
+// Get is used to get a key/value from the map.
+// Returns an error if the key does not exist.
+func (m *ConcurrentSortedMap[K, V]) Get(key K) (V, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	node := m.find(key)
+
+	if node == nil {
+		var zero V
+		return zero, &KeyError{Op: "ConcurrentSortedMap: Cannot get map value", Key: fmt.Sprint(key), Err: ErrKeyNotFound}
+	}
+
+	return node.value, nil
+}
+
+// Set is used to change an existing key/value in the map.
+// Returns an error if the key does not exist.
+func (m *ConcurrentSortedMap[K, V]) Set(key K, value V) error {
+	m.Lock()
+	defer m.Unlock()
+
+	node := m.find(key)
+
+	if node == nil {
+		return &KeyError{Op: "ConcurrentSortedMap: Cannot set map value", Key: fmt.Sprint(key), Err: ErrKeyNotFound}
+	}
+
+	node.value = value
+	return nil
+}
+
+// Exists is used by external callers to check if a value
+// exists in the map and returns a boolean with the result.
+func (m *ConcurrentSortedMap[K, V]) Exists(key K) bool {
+	m.RLock()
+	defer m.RUnlock()
+
+	return m.find(key) != nil
+}
+
+// Floor returns the entry with the greatest key less than or equal to key.
+// The boolean result is false if there is no such entry.
+func (m *ConcurrentSortedMap[K, V]) Floor(key K) (K, V, bool) {
+	m.RLock()
+	defer m.RUnlock()
+
+	var update [skipMaxLevel]*skipNode[K, V]
+	node := m.seek(key, &update)
+
+	if node == nil || m.less(key, node.key) {
+		node = update[0]
+	}
+
+	if node == m.head {
+		var k K
+		var v V
+		return k, v, false
+	}
+
+	return node.key, node.value, true
+}
+
+// Ceiling returns the entry with the least key greater than or equal to key.
+// The boolean result is false if there is no such entry.
+func (m *ConcurrentSortedMap[K, V]) Ceiling(key K) (K, V, bool) {
+	m.RLock()
+	defer m.RUnlock()
+
+	node := m.seek(key, nil)
+
+	if node == nil {
+		var k K
+		var v V
+		return k, v, false
+	}
+
+	return node.key, node.value, true
+}
+
+// Min returns the entry with the least key.
+// The boolean result is false if the map is empty.
+func (m *ConcurrentSortedMap[K, V]) Min() (K, V, bool) {
+	m.RLock()
+	defer m.RUnlock()
+
+	node := m.head.next[0]
+
+	if node == nil {
+		var k K
+		var v V
+		return k, v, false
+	}
+
+	return node.key, node.value, true
+}
+
+// Max returns the entry with the greatest key.
+// The boolean result is false if the map is empty.
+func (m *ConcurrentSortedMap[K, V]) Max() (K, V, bool) {
+	m.RLock()
+	defer m.RUnlock()
+
+	x := m.head
+	for i := m.level - 1; i >= 0; i-- {
+		for x.next[i] != nil {
+			x = x.next[i]
+		}
+	}
+
+	if x == m.head {
+		var k K
+		var v V
+		return k, v, false
+	}
+
+	return x.key, x.value, true
+}
+
+// Range returns an iterator over the entries with keys from from (inclusive) to
+// to (exclusive) in ascending order, for use with range. The matching entries are
+// copied out under the read lock when the iteration starts, so the loop body runs
+// without the lock held and is free to modify the map.
+func (m *ConcurrentSortedMap[K, V]) Range(from, to K) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		type entry struct {
+			key   K
+			value V
+		}
+
+		var entries []entry
+
+		m.RLock()
+		for x := m.seek(from, nil); x != nil && m.less(x.key, to); x = x.next[0] {
+			entries = append(entries, entry{x.key, x.value})
+		}
+		m.RUnlock()
+
+		for _, e := range entries {
+			if !yield(e.key, e.value) {
+				return
+			}
+		}
+	}
+}
@@ -27,56 +27,263 @@
 package util
 
 import (
-	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
+	"iter"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"unicode"
+	"unicode/utf8"
 )
 
 // ChunkJoinStrings takes a list of individual parameters and joins them to strings
-// separated by sep, limited by the maxlength. For each item, if appending the item
-// would breach the maxlength, it instead starts to build a new string. Once all of
-// the strings are built, it returns the list of strings.
+// separated by sep, each at most maxlength bytes long. For each item, if appending
+// the item would breach the maxlength, it instead starts to build a new string.
+// Items longer than maxlength on their own are split across strings, at rune
+// boundaries. Once all of the strings are built, it returns the list of strings.
+// A maxlength of zero or less means there is no limit.
 func ChunkJoinStrings(params []string, maxlength int, sep string) []string {
-	var buffer bytes.Buffer
+	joined, _ := ChunkJoinStringsWith(params, maxlength, sep) // Splitting never fails.
+	return joined
+}
+
+// ChunkJoinRunes is the same as ChunkJoinStrings, but measures the maxlength in runes
+// rather than bytes, so multi-byte UTF-8 text is limited by its number of characters.
+func ChunkJoinRunes(params []string, maxlength int, sep string) []string {
+	joined, _ := ChunkJoinStringsWith(params, maxlength, sep, WithRuneCount())
+	return joined
+}
+
+// ChunkJoinBytes is the byte slice equivalent of ChunkJoinStrings, joining the items
+// separated by sep into slices each at most maxlength bytes long. Items longer than
+// maxlength on their own are split across slices. The slices share a single backing
+// array, sized up front, so no allocations are made per item.
+func ChunkJoinBytes(params [][]byte, maxlength int, sep []byte) [][]byte {
+	total := 0
+	for _, param := range params {
+		total += len(param) + len(sep)
+	}
+
+	out := make([]byte, 0, total) // Never reallocated, so the slices stay valid.
+	joined := [][]byte{}
+	start := 0
+	started := false // Whether the current slice has an item, which may be empty.
+
+	flush := func() {
+		if started {
+			joined = append(joined, out[start:len(out):len(out)])
+			start = len(out)
+			started = false
+		}
+	}
+
+	for _, param := range params {
+		// Check if we have enough room to write the item after a separator
+		if started && (maxlength <= 0 || len(out)-start+len(sep)+len(param) <= maxlength) {
+			out = append(out, sep...)
+			out = append(out, param...)
+			continue
+		}
+
+		// Not enough room, start the next slice with the item, split if it won't fit
+		flush()
+		for maxlength > 0 && len(param) > maxlength {
+			out = append(out, param[:maxlength]...)
+			started = true
+			flush()
+			param = param[maxlength:]
+		}
+		out = append(out, param...)
+		started = true
+	}
+
+	flush() // Finished iterating without hitting max length on the current pass.
+
+	return joined
+}
+
+// ChunkString breaks one long string into pieces at most maxlength bytes long,
+// preferring to end each piece just after a rune for which boundary returns true,
+// and only splitting elsewhere, at a rune boundary, when a piece has none.
+// A nil boundary breaks after whitespace. Joining the pieces gives back s.
+func ChunkString(s string, maxlength int, boundary func(rune) bool) []string {
+	return chunkString(s, maxlength, boundary, byteMeasure)
+}
+
+// textMeasure is how the chunking and wrapping helpers measure text.
+type textMeasure struct {
+	length func(s string) int
+	unit   func(s string, i int) int // Length in bytes of the unit at s[i], which is never split.
+}
+
+// Measures by bytes and by runes, both of which split text between runes.
+var (
+	byteMeasure = textMeasure{length: func(s string) int { return len(s) }, unit: runeLenAt}
+	runeMeasure = textMeasure{length: utf8.RuneCountInString, unit: runeLenAt}
+)
+
+// runeLenAt returns the length in bytes of the rune starting at s[i].
+func runeLenAt(s string, i int) int {
+	_, size := utf8.DecodeRuneInString(s[i:])
+	return size
+}
+
+// chunkString implements ChunkString, measuring the pieces with measure.
+func chunkString(s string, maxlength int, boundary func(rune) bool, measure textMeasure) []string {
+	length := measure.length
+	if maxlength <= 0 || length(s) <= maxlength {
+		return []string{s}
+	}
+	if boundary == nil {
+		boundary = unicode.IsSpace
+	}
+
+	var pieces []string
+	start, lastBreak := 0, 0 // lastBreak is after the last boundary in the piece, or start if none.
+
+	for i := 0; i < len(s); {
+		size := measure.unit(s, i)
+
+		for i > start && length(s[start:i+size]) > maxlength {
+			cut := i
+			if lastBreak > start {
+				cut = lastBreak
+			}
+			pieces = append(pieces, s[start:cut])
+			start, lastBreak = cut, cut
+		}
+
+		if r, runeSize := utf8.DecodeRuneInString(s[i:]); runeSize == size && boundary(r) {
+			lastBreak = i + size
+		}
+		i += size
+	}
+
+	return append(pieces, s[start:])
+}
+
+// chunkJoin implements ChunkJoinStringsWith.
+func chunkJoin(params []string, maxlength int, sep string, config chunkConfig) ([]string, error) {
+	if config.prefix == "" && config.continuation == "" {
+		return chunkJoinItems(params, maxlength, sep, config)
+	}
+
+	// Keep room in each string for the prefix and continuation marker.
+	if maxlength > 0 {
+		maxlength = max(maxlength-config.measure.length(config.prefix)-config.measure.length(config.continuation), 1)
+	}
+
+	joined, err := chunkJoinItems(params, maxlength, sep, config)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range joined {
+		if i+1 < len(joined) {
+			joined[i] = config.prefix + joined[i] + config.continuation
+		} else {
+			joined[i] = config.prefix + joined[i]
+		}
+	}
+
+	return joined, nil
+}
+
+// chunkJoinItems joins the items for chunkJoin, before any decoration.
+func chunkJoinItems(params []string, maxlength int, sep string, config chunkConfig) ([]string, error) {
+	var buffer strings.Builder
 	currlen := 0
+	started := false // Whether the current string has an item, which may be empty.
 	joined := []string{}
-	iterate := false
+	seplen := config.measure.length(sep)
+
+	flush := func() {
+		if started {
+			joined = append(joined, buffer.String())
+			buffer.Reset()
+			currlen = 0
+			started = false
+		}
+	}
 
 	for i, param := range params {
-		// Check if we have enough room to write the item
-		if currlen+len(param) < maxlength {
+		length := config.measure.length(param)
+
+		// Check if we have enough room to write the item after a separator
+		if started && (maxlength <= 0 || currlen+seplen+length <= maxlength) {
+			buffer.WriteString(sep)
 			buffer.WriteString(param)
-			currlen += len(param)
-		} else { // Not enough room, reiterate for the next item
-			iterate = true
+			currlen += seplen + length
+			continue
 		}
 
-		// Check if last item or if we can fit a space
-		if i+1 < len(params) && currlen+len(sep) < maxlength {
-			buffer.WriteString(sep)
-			currlen++
-		} else { // Not enough room, reiterate for the next item
-			iterate = true
+		// Not enough room, start the next string with the item
+		if maxlength <= 0 || length <= maxlength {
+			flush()
+			buffer.WriteString(param)
+			currlen = length
+			started = true
+			continue
 		}
 
-		if iterate {
-			currlen = 0
-			iterate = false
-			joined = append(joined, buffer.String())
-			buffer.Reset()
+		// The item won't fit in any string
+		switch config.oversize {
+		case OversizeError:
+			return nil, fmt.Errorf("ChunkJoinStrings: Cannot fit item %d, %w", i, ErrItemTooLong)
+		case OversizeAlone:
+			flush()
+			joined = append(joined, param)
+		default:
+			flush()
+			pieces := splitByLength(param, maxlength, config.measure)
+			joined = append(joined, pieces[:len(pieces)-1]...)
+
+			// The remainder may share its string with the following items.
+			last := pieces[len(pieces)-1]
+			buffer.WriteString(last)
+			currlen = config.measure.length(last)
+			started = true
 		}
 	}
 
-	if buffer.Len() > 0 { // Finished iterating without hitting max length on the current pass.
-		joined = append(joined, buffer.String())
+	flush() // Finished iterating without hitting max length on the current pass.
+
+	return joined, nil
+}
+
+// splitByLength splits s between the units of measure into pieces at most maxlength
+// long. A unit which is longer than maxlength on its own gets a piece to itself.
+func splitByLength(s string, maxlength int, measure textMeasure) []string {
+	var pieces []string
+	start, currlen := 0, 0
+
+	for i := 0; i < len(s); {
+		size := measure.unit(s, i)
+		unit := measure.length(s[i : i+size])
+		if currlen+unit > maxlength && i > start {
+			pieces = append(pieces, s[start:i])
+			start, currlen = i, 0
+		}
+		currlen += unit
+		i += size
 	}
 
-	return joined
+	return append(pieces, s[start:])
 }
 
 // ConcurrentMapString is a simple map[string]string wrapped with a concurrent-safe API
 type ConcurrentMapString struct {
-	data map[string]string
+	data      map[string]string
+	watchers  map[*mapWatcher]struct{}
+	stats     *mapStats
+	inflight  map[string]*mapCall
+	factory   func(string) string
+	normalize func(string) string
+	journal   *mapJournal
+	contended atomic.Uint64
 	sync.RWMutex
 }
 
@@ -88,6 +295,91 @@ func NewConcurrentMapString() *ConcurrentMapString {
 	return m
 }
 
+// NewConcurrentMapWithDefault initializes and returns a pointer to a new ConcurrentMapString
+// instance where Get on a missing key stores and returns the result of factory for that key.
+// The factory is called with the map's lock held and must not use the map.
+func NewConcurrentMapWithDefault(factory func(key string) string) *ConcurrentMapString {
+	m := NewConcurrentMapString()
+	m.factory = factory
+	return m
+}
+
+// NewConcurrentMapStringNormalized initializes and returns a pointer to a new ConcurrentMapString
+// instance which passes every key through normalize before using it, so that keys which
+// normalize to the same string refer to the same entry. Keys are stored in their
+// normalized form, which is what ForEach, Keys and the other accessors return.
+func NewConcurrentMapStringNormalized(normalize func(key string) string) *ConcurrentMapString {
+	m := NewConcurrentMapString()
+	m.normalize = normalize
+	return m
+}
+
+// NewCaselessConcurrentMapString initializes and returns a pointer to a new ConcurrentMapString
+// instance with case-insensitive keys, which are stored in lower case.
+func NewCaselessConcurrentMapString() *ConcurrentMapString {
+	return NewConcurrentMapStringNormalized(strings.ToLower)
+}
+
+// normalizeKey returns the key as it is stored in the map.
+func (m *ConcurrentMapString) normalizeKey(key string) string {
+	if m.normalize == nil {
+		return key
+	}
+	return m.normalize(key)
+}
+
+// Lock locks the map for writing, counting the acquisition as contended
+// if the lock was already held.
+func (m *ConcurrentMapString) Lock() {
+	if !m.RWMutex.TryLock() {
+		m.contended.Add(1)
+		m.RWMutex.Lock()
+	}
+}
+
+// RLock locks the map for reading, counting the acquisition as contended
+// if the lock was already held for writing.
+func (m *ConcurrentMapString) RLock() {
+	if !m.RWMutex.TryRLock() {
+		m.contended.Add(1)
+		m.RWMutex.RLock()
+	}
+}
+
+// Contention returns the number of times a lock on the map could not be
+// acquired immediately and had to wait for another holder.
+func (m *ConcurrentMapString) Contention() uint64 {
+	return m.contended.Load()
+}
+
+// store writes the key/value to the underlying map and notifies any watchers.
+// All writes to the map go through store. Must be called with the lock held.
+func (m *ConcurrentMapString) store(key string, value string) {
+	old, exists := m.data[key]
+	m.data[key] = value
+
+	if exists {
+		m.stats.recordSet()
+		m.journal.record(MapEventSet, key, old, value)
+		m.notify(MapEventSet, key, value)
+	} else {
+		m.stats.recordAdd()
+		m.journal.record(MapEventAdd, key, "", value)
+		m.notify(MapEventAdd, key, value)
+	}
+}
+
+// remove deletes the key from the underlying map and notifies any watchers.
+// All deletes from the map go through remove. Must be called with the lock held.
+func (m *ConcurrentMapString) remove(key string) {
+	old := m.data[key]
+	delete(m.data, key)
+
+	m.stats.recordDel()
+	m.journal.record(MapEventDel, key, old, "")
+	m.notify(MapEventDel, key, old)
+}
+
 // ForEach will call the provided function for each entry in the ConcurrentMapString
 func (m *ConcurrentMapString) ForEach(do func(string, string)) {
 	m.RLock()
@@ -98,6 +390,39 @@ func (m *ConcurrentMapString) ForEach(do func(string, string)) {
 	}
 }
 
+// ForEachUntil will call the provided function for each entry in the ConcurrentMapString
+// until it returns true, at which point the iteration stops early.
+// Returns true if the iteration was stopped by the provided function.
+func (m *ConcurrentMapString) ForEachUntil(do func(string, string) bool) bool {
+	m.RLock()
+	defer m.RUnlock()
+
+	for key, val := range m.data {
+		if do(key, val) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ForEachSnapshot will call the provided function for each entry in the ConcurrentMapString
+// as it was when ForEachSnapshot was called. The entries are copied under the read lock and
+// the function runs without it, so a slow callback doesn't block writers and is free to
+// modify the map. Writes made during the iteration are not observed.
+func (m *ConcurrentMapString) ForEachSnapshot(do func(string, string)) {
+	m.RLock()
+	entries := make([][2]string, 0, len(m.data))
+	for key, val := range m.data {
+		entries = append(entries, [2]string{key, val})
+	}
+	m.RUnlock()
+
+	for _, entry := range entries {
+		do(entry[0], entry[1])
+	}
+}
+
 // Length returns the length of the underlying map.
 func (m *ConcurrentMapString) Length() int {
 	m.RLock()
@@ -107,66 +432,113 @@ func (m *ConcurrentMapString) Length() int {
 }
 
 // Add is used to add a key/value to the map.
-// Returns an error if the key already exists.
+// Returns a KeyError wrapping ErrKeyExists if the key already exists.
 func (m *ConcurrentMapString) Add(key string, value string) error {
 	m.Lock()
 	defer m.Unlock()
 
+	return m.add(key, value)
+}
+
+// add is the implementation of Add. Must be called with the lock held.
+func (m *ConcurrentMapString) add(key string, value string) error {
+	key = m.normalizeKey(key)
+
 	_, exists := m.data[key]
 
 	if exists {
-		return fmt.Errorf("ConcurrentMapString: Cannot add map entry, key already exists: %q", key)
+		return &KeyError{Op: "ConcurrentMapString: Cannot add map entry", Key: key, Err: ErrKeyExists}
 	}
 
-	m.data[key] = value
+	m.store(key, value)
 	return nil
 }
 
 // Del is used to remove a key/value from the map.
-// Returns an error if the key does not exist.
+// Returns a KeyError wrapping ErrKeyNotFound if the key does not exist.
 func (m *ConcurrentMapString) Del(key string) error {
 	m.Lock()
 	defer m.Unlock()
 
+	return m.del(key)
+}
+
+// del is the implementation of Del. Must be called with the lock held.
+func (m *ConcurrentMapString) del(key string) error {
+	key = m.normalizeKey(key)
+
 	_, exists := m.data[key]
 
 	if !exists {
-		return fmt.Errorf("ConcurrentMapString: Cannot delete map entry, key does not exist: %q", key)
+		return &KeyError{Op: "ConcurrentMapString: Cannot delete map entry", Key: key, Err: ErrKeyNotFound}
 	}
 
-	delete(m.data, key)
+	m.remove(key)
 
 	return nil
 }
 
 // Get is used to get a key/value from the map.
-// Returns an error if the key does not exist.
+// Returns a KeyError wrapping ErrKeyNotFound if the key does not exist, unless the
+// map was created with NewConcurrentMapWithDefault, in which case the default
+// value for the key is stored and returned instead.
 func (m *ConcurrentMapString) Get(key string) (string, error) {
-	m.RLock()
-	defer m.RUnlock()
+	return m.get(key, true)
+}
 
+// get is the implementation of Get, which only stores and returns the default
+// value for a missing key if useDefault is set.
+func (m *ConcurrentMapString) get(key string, useDefault bool) (string, error) {
+	key = m.normalizeKey(key)
+
+	m.RLock()
 	v, exists := m.data[key]
+	m.RUnlock()
 
-	if !exists {
-		return "", fmt.Errorf("ConcurrentMapString: Cannot get map value, key does not exist: %q", key)
+	m.stats.recordGet(exists)
+
+	if exists {
+		return v, nil
+	}
+
+	if m.factory == nil || !useDefault {
+		return "", &KeyError{Op: "ConcurrentMapString: Cannot get map value", Key: key, Err: ErrKeyNotFound}
 	}
 
+	m.Lock()
+	defer m.Unlock()
+
+	// Another caller may have stored the key while the lock was released.
+	if v, exists = m.data[key]; exists {
+		return v, nil
+	}
+
+	v = m.factory(key)
+	m.store(key, v)
+
 	return v, nil
 }
 
 // Set is used to change an existing key/value in the map.
-// Returns an error if the key does not exist.
+// Returns a KeyError wrapping ErrKeyNotFound if the key does not exist.
 func (m *ConcurrentMapString) Set(key string, value string) error {
 	m.Lock()
 	defer m.Unlock()
 
+	return m.set(key, value)
+}
+
+// set is the implementation of Set. Must be called with the lock held.
+func (m *ConcurrentMapString) set(key string, value string) error {
+	key = m.normalizeKey(key)
+
 	_, exists := m.data[key]
 
 	if !exists {
-		return fmt.Errorf("ConcurrentMapString: Cannot set map value, key does not exist: %q", key)
+		return &KeyError{Op: "ConcurrentMapString: Cannot set map value", Key: key, Err: ErrKeyNotFound}
 	}
 
-	m.data[key] = value
+	m.store(key, value)
 
 	return nil
 }
@@ -174,9 +546,522 @@ func (m *ConcurrentMapString) Set(key string, value string) error {
 // Exists is used by external callers to check if a value
 // exists in the map and returns a boolean with the result.
 func (m *ConcurrentMapString) Exists(key string) bool {
+	key = m.normalizeKey(key)
+
 	m.RLock()
 	defer m.RUnlock()
 
 	_, exists := m.data[key]
 	return exists
 }
+
+// GetOrSet is used to get the value of a key from the map, adding the given value
+// if the key does not exist yet. The check and the write happen under a single lock.
+// Returns the value now in the map, and true if it was already present.
+func (m *ConcurrentMapString) GetOrSet(key string, value string) (string, bool) {
+	key = m.normalizeKey(key)
+
+	m.Lock()
+	defer m.Unlock()
+
+	v, exists := m.data[key]
+	m.stats.recordGet(exists)
+
+	if exists {
+		return v, true
+	}
+
+	m.store(key, value)
+	return value, false
+}
+
+// Upsert is used to add or change a key/value in the map based on its current state.
+// The provided function receives the existing value, if any, and whether the key exists,
+// and its result is stored under the key while the lock is held. Returns the stored value.
+func (m *ConcurrentMapString) Upsert(key string, update func(old string, exists bool) string) string {
+	key = m.normalizeKey(key)
+
+	m.Lock()
+	defer m.Unlock()
+
+	old, exists := m.data[key]
+	value := update(old, exists)
+	m.store(key, value)
+
+	return value
+}
+
+// CompareAndSwap is used to change the value of a key only if its current value is old.
+// Returns true if the swap was performed.
+func (m *ConcurrentMapString) CompareAndSwap(key string, old string, new string) bool {
+	key = m.normalizeKey(key)
+
+	m.Lock()
+	defer m.Unlock()
+
+	v, exists := m.data[key]
+
+	if !exists || v != old {
+		return false
+	}
+
+	m.store(key, new)
+	return true
+}
+
+// CompareAndDelete is used to remove a key/value only if its current value is old.
+// Returns true if the entry was deleted.
+func (m *ConcurrentMapString) CompareAndDelete(key string, old string) bool {
+	key = m.normalizeKey(key)
+
+	m.Lock()
+	defer m.Unlock()
+
+	v, exists := m.data[key]
+
+	if !exists || v != old {
+		return false
+	}
+
+	m.remove(key)
+	return true
+}
+
+// Snapshot returns a copy of the underlying map taken under the read lock,
+// which the caller is free to iterate or modify without holding any lock.
+func (m *ConcurrentMapString) Snapshot() map[string]string {
+	m.RLock()
+	defer m.RUnlock()
+
+	snap := make(map[string]string, len(m.data))
+	for key, val := range m.data {
+		snap[key] = val
+	}
+
+	return snap
+}
+
+// Clone returns a new ConcurrentMapString holding a copy of the current entries,
+// with the same default factory and key normalization.
+func (m *ConcurrentMapString) Clone() *ConcurrentMapString {
+	return m.derive(m.Snapshot())
+}
+
+// derive returns a new ConcurrentMapString holding data, with the same default
+// factory and key normalization as m.
+func (m *ConcurrentMapString) derive(data map[string]string) *ConcurrentMapString {
+	return &ConcurrentMapString{
+		data:      data,
+		factory:   m.factory,
+		normalize: m.normalize,
+	}
+}
+
+// Keys returns a slice of all of the keys currently in the map, in no particular order.
+func (m *ConcurrentMapString) Keys() []string {
+	m.RLock()
+	defer m.RUnlock()
+
+	keys := make([]string, 0, len(m.data))
+	for key := range m.data {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+// Values returns a slice of all of the values currently in the map, in no particular order.
+func (m *ConcurrentMapString) Values() []string {
+	m.RLock()
+	defer m.RUnlock()
+
+	vals := make([]string, 0, len(m.data))
+	for _, val := range m.data {
+		vals = append(vals, val)
+	}
+
+	return vals
+}
+
+// MarshalJSON implements json.Marshaler, encoding the map as a JSON object
+// while holding the read lock.
+func (m *ConcurrentMapString) MarshalJSON() ([]byte, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	return json.Marshal(m.data)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, replacing the contents of the map
+// with the decoded JSON object. Only the entries which differ are written, and
+// watchers, stats and the journal see them like any other write. The map is left
+// untouched if decoding fails.
+func (m *ConcurrentMapString) UnmarshalJSON(b []byte) error {
+	data := make(map[string]string)
+
+	if err := json.Unmarshal(b, &data); err != nil {
+		return err
+	}
+
+	if m.normalize != nil {
+		normalized := make(map[string]string, len(data))
+		for key, val := range data {
+			normalized[m.normalize(key)] = val
+		}
+		data = normalized
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	m.replace(data)
+	return nil
+}
+
+// replace makes the contents of the map equal to data, removing and storing only
+// the entries which differ. The keys must already be normalized. Must be called
+// with the lock held.
+func (m *ConcurrentMapString) replace(data map[string]string) {
+	if m.data == nil {
+		m.data = make(map[string]string, len(data)) // Decoding into a zero value.
+	}
+
+	for key := range m.data {
+		if _, keep := data[key]; !keep {
+			m.remove(key)
+		}
+	}
+
+	for key, val := range data {
+		if old, exists := m.data[key]; !exists || old != val {
+			m.store(key, val)
+		}
+	}
+}
+
+// AddMany is used to add several key/values to the map under a single lock acquisition.
+// Returns the error for each key which could not be added, or nil if all of them were.
+func (m *ConcurrentMapString) AddMany(entries map[string]string) map[string]error {
+	m.Lock()
+	defer m.Unlock()
+
+	var errs map[string]error
+	for key, val := range entries {
+		if err := m.add(key, val); err != nil {
+			if errs == nil {
+				errs = make(map[string]error)
+			}
+			errs[key] = err
+		}
+	}
+
+	return errs
+}
+
+// SetMany is used to change several existing key/values in the map under a single lock
+// acquisition. Returns the error for each key which could not be set, or nil if all of them were.
+func (m *ConcurrentMapString) SetMany(entries map[string]string) map[string]error {
+	m.Lock()
+	defer m.Unlock()
+
+	var errs map[string]error
+	for key, val := range entries {
+		if err := m.set(key, val); err != nil {
+			if errs == nil {
+				errs = make(map[string]error)
+			}
+			errs[key] = err
+		}
+	}
+
+	return errs
+}
+
+// DelMany is used to remove several key/values from the map under a single lock acquisition.
+// Returns the error for each key which could not be deleted, or nil if all of them were.
+func (m *ConcurrentMapString) DelMany(keys []string) map[string]error {
+	m.Lock()
+	defer m.Unlock()
+
+	var errs map[string]error
+	for _, key := range keys {
+		if err := m.del(key); err != nil {
+			if errs == nil {
+				errs = make(map[string]error)
+			}
+			errs[key] = err
+		}
+	}
+
+	return errs
+}
+
+// GetMany is used to get several key/values from the map under a single lock acquisition.
+// Returns a map holding each of the requested keys that exist; missing keys are omitted.
+func (m *ConcurrentMapString) GetMany(keys []string) map[string]string {
+	m.RLock()
+	defer m.RUnlock()
+
+	found := make(map[string]string, len(keys))
+	for _, key := range keys {
+		val, exists := m.data[m.normalizeKey(key)]
+		m.stats.recordGet(exists)
+
+		if exists {
+			found[key] = val
+		}
+	}
+
+	return found
+}
+
+// Update is used to add or change several key/values in the map under a single lock acquisition.
+func (m *ConcurrentMapString) Update(entries map[string]string) {
+	m.Lock()
+	defer m.Unlock()
+
+	for key, val := range entries {
+		m.store(m.normalizeKey(key), val)
+	}
+}
+
+// Merge combines the entries of other into the map. Keys present in only one map
+// are kept as-is; for keys present in both, resolve is called with the key, the value
+// from this map and the value from other, and its result is stored. A nil resolve
+// means values from other win. The entries of other are copied under its read lock
+// first, then applied under this map's lock so the merge is observed all at once.
+func (m *ConcurrentMapString) Merge(other *ConcurrentMapString, resolve func(key, a, b string) string) {
+	incoming := other.Snapshot()
+
+	m.Lock()
+	defer m.Unlock()
+
+	for key, val := range incoming {
+		key = m.normalizeKey(key)
+
+		if resolve != nil {
+			if existing, exists := m.data[key]; exists {
+				val = resolve(key, existing, val)
+			}
+		}
+		m.store(key, val)
+	}
+}
+
+// Filter returns a new ConcurrentMapString holding only the entries for which keep
+// returns true. The predicate is run over a snapshot, outside of the map's lock.
+func (m *ConcurrentMapString) Filter(keep func(string, string) bool) *ConcurrentMapString {
+	data := m.Snapshot()
+
+	for key, val := range data {
+		if !keep(key, val) {
+			delete(data, key)
+		}
+	}
+
+	return m.derive(data)
+}
+
+// MapValues returns a new ConcurrentMapString holding the same keys with each value
+// replaced by the result of transform. The transform is run over a snapshot,
+// outside of the map's lock.
+func (m *ConcurrentMapString) MapValues(transform func(string, string) string) *ConcurrentMapString {
+	data := m.Snapshot()
+
+	for key, val := range data {
+		data[key] = transform(key, val)
+	}
+
+	return m.derive(data)
+}
+
+// All returns an iterator over the entries of the map for use with range.
+// The iteration runs over a snapshot taken when it starts, so the loop body
+// runs without the lock held and is free to modify the map.
+func (m *ConcurrentMapString) All() iter.Seq2[string, string] {
+	return func(yield func(string, string) bool) {
+		for key, val := range m.Snapshot() {
+			if !yield(key, val) {
+				return
+			}
+		}
+	}
+}
+
+// SaveTo writes the contents of the map to w as a JSON object.
+func (m *ConcurrentMapString) SaveTo(w io.Writer) error {
+	return json.NewEncoder(w).Encode(m)
+}
+
+// LoadFrom replaces the contents of the map with the JSON object read from r,
+// as written by SaveTo. The map is left untouched if decoding fails.
+func (m *ConcurrentMapString) LoadFrom(r io.Reader) error {
+	return json.NewDecoder(r).Decode(m)
+}
+
+// Clear is used to remove all of the key/values from the map.
+func (m *ConcurrentMapString) Clear() {
+	m.Lock()
+	defer m.Unlock()
+
+	for key := range m.data {
+		m.remove(key)
+	}
+}
+
+// Pop is used to get a key/value from the map and remove it under a single lock.
+// Returns a KeyError wrapping ErrKeyNotFound if the key does not exist.
+func (m *ConcurrentMapString) Pop(key string) (string, error) {
+	key = m.normalizeKey(key)
+
+	m.Lock()
+	defer m.Unlock()
+
+	v, exists := m.data[key]
+
+	if !exists {
+		return "", &KeyError{Op: "ConcurrentMapString: Cannot pop map value", Key: key, Err: ErrKeyNotFound}
+	}
+
+	m.remove(key)
+
+	return v, nil
+}
+
+// mapCall is an in-progress GetOrCompute loader which other callers can wait on.
+type mapCall struct {
+	done  chan struct{}
+	value string
+	err   error
+}
+
+// GetOrCompute is used to get a key/value from the map, calling loader to produce
+// the value and storing it if the key does not exist. When many goroutines miss
+// on the same key at once, loader is only run by one of them and the rest wait
+// for its result. The map's lock is not held while loader runs. A loader error is
+// returned to all of the waiting callers and nothing is stored.
+func (m *ConcurrentMapString) GetOrCompute(key string, loader func() (string, error)) (string, error) {
+	key = m.normalizeKey(key)
+
+	m.RLock()
+	v, exists := m.data[key]
+	m.RUnlock()
+
+	if exists {
+		m.stats.recordGet(true)
+		return v, nil
+	}
+
+	m.Lock()
+
+	v, exists = m.data[key]
+	m.stats.recordGet(exists)
+
+	if exists {
+		m.Unlock()
+		return v, nil
+	}
+
+	if call, running := m.inflight[key]; running {
+		m.Unlock()
+		<-call.done
+		return call.value, call.err
+	}
+
+	call := &mapCall{done: make(chan struct{})}
+	if m.inflight == nil {
+		m.inflight = make(map[string]*mapCall)
+	}
+	m.inflight[key] = call
+	m.Unlock()
+
+	m.compute(key, call, loader)
+
+	return call.value, call.err
+}
+
+// compute runs the loader for an in-progress GetOrCompute call, stores its result and
+// releases any waiters. Waiters are released with an error if the loader panics.
+func (m *ConcurrentMapString) compute(key string, call *mapCall, loader func() (string, error)) {
+	defer func() {
+		m.Lock()
+		delete(m.inflight, key)
+		if call.err == nil {
+			// Keep any value written while the loader was running.
+			if existing, exists := m.data[key]; exists {
+				call.value = existing
+			} else {
+				m.store(key, call.value)
+			}
+		}
+		m.Unlock()
+		close(call.done)
+	}()
+
+	call.err = fmt.Errorf("ConcurrentMapString: Cannot compute map value, loader panicked: %q", key)
+	call.value, call.err = loader()
+}
+
+// GetAndDelete is used to get a key/value from the map and remove it under a single lock.
+// Returns the removed value and true, or false if the key did not exist.
+func (m *ConcurrentMapString) GetAndDelete(key string) (string, bool) {
+	key = m.normalizeKey(key)
+
+	m.Lock()
+	defer m.Unlock()
+
+	v, exists := m.data[key]
+
+	if exists {
+		m.remove(key)
+	}
+
+	return v, exists
+}
+
+// DeleteIf is used to remove every key/value for which the provided function returns true,
+// under a single lock acquisition. The function is called with the lock held and must not
+// use the map. Returns the number of entries removed.
+func (m *ConcurrentMapString) DeleteIf(pred func(string, string) bool) int {
+	m.Lock()
+	defer m.Unlock()
+
+	count := 0
+	for key, val := range m.data {
+		if pred(key, val) {
+			m.remove(key)
+			count++
+		}
+	}
+
+	return count
+}
+
+// SortedKeys returns a slice of all of the keys currently in the map, ordered by less.
+func (m *ConcurrentMapString) SortedKeys(less func(a, b string) bool) []string {
+	keys := m.Keys()
+	sort.Slice(keys, func(i, j int) bool {
+		return less(keys[i], keys[j])
+	})
+	return keys
+}
+
+// ForEachSorted will call the provided function for each entry in the ConcurrentMapString
+// in the order given by less. The entries are copied under the read lock and the function
+// runs without it, as with ForEachSnapshot.
+func (m *ConcurrentMapString) ForEachSorted(less func(a, b string) bool, do func(string, string)) {
+	snap := m.Snapshot()
+
+	keys := make([]string, 0, len(snap))
+	for key := range snap {
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return less(keys[i], keys[j])
+	})
+
+	for _, key := range keys {
+		do(key, snap[key])
+	}
+}
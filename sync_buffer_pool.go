@@ -0,0 +1,82 @@
+/*
+   Copyright (c) 2020, btnmasher
+   All rights reserved.
+
+   Redistribution and use in source and binary forms, with or without modification, are permitted provided that
+   the following conditions are met:
+
+   1. Redistributions of source code must retain the above copyright notice, this list of conditions and the
+      following disclaimer.
+
+   2. Redistributions in binary form must reproduce the above copyright notice, this list of conditions and
+      the following disclaimer in the documentation and/or other materials provided with the distribution.
+
+   3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse or
+      promote products derived from this software without specific prior written permission.
+
+   THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED
+   WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+   PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR
+   ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+   TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+   HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+   NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+   POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package util
+
+import (
+	"bytes"
+	"sync"
+)
+
+// BufferSource is the API shared by the Buffer pools in this package, so code can
+// take whichever pool implementation suits its workload.
+type BufferSource interface {
+	New() *bytes.Buffer
+	Recycle(buf *bytes.Buffer)
+}
+
+var (
+	_ BufferSource = (*BufferPool)(nil)
+	_ BufferSource = (*SyncBufferPool)(nil)
+)
+
+// SyncBufferPool is a pool of bytes.Buffer backed by a sync.Pool. Unlike BufferPool
+// it has no fixed size: it caches Buffers per processor, and the garbage collector
+// is free to release idle Buffers, which suits workloads with bursty demand.
+type SyncBufferPool struct {
+	pool sync.Pool
+
+	// MaxCapacity is the largest capacity of a Buffer which will be kept by Recycle.
+	// Zero means there is no limit.
+	MaxCapacity int
+}
+
+// NewSyncBufferPool creates a new sync.Pool backed pool of bytes.Buffer.
+func NewSyncBufferPool() *SyncBufferPool {
+	return &SyncBufferPool{
+		pool: sync.Pool{
+			New: func() any {
+				return &bytes.Buffer{}
+			},
+		},
+	}
+}
+
+// New takes a Buffer from the pool.
+func (pool *SyncBufferPool) New() *bytes.Buffer {
+	return pool.pool.Get().(*bytes.Buffer)
+}
+
+// Recycle returns a Buffer to the pool.
+// Buffers which have grown beyond MaxCapacity are dropped instead.
+func (pool *SyncBufferPool) Recycle(buf *bytes.Buffer) {
+	if pool.MaxCapacity > 0 && buf.Cap() > pool.MaxCapacity {
+		return
+	}
+
+	buf.Reset()
+	pool.pool.Put(buf)
+}
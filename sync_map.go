@@ -0,0 +1,141 @@
+/*
+   Copyright (c) 2020, btnmasher
+   All rights reserved.
+
+   Redistribution and use in source and binary forms, with or without modification, are permitted provided that
+   the following conditions are met:
+
+   1. Redistributions of source code must retain the above copyright notice, this list of conditions and the
+      following disclaimer.
+
+   2. Redistributions in binary form must reproduce the above copyright notice, this list of conditions and
+      the following disclaimer in the documentation and/or other materials provided with the distribution.
+
+   3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse or
+      promote products derived from this software without specific prior written permission.
+
+   THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED
+   WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+   PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR
+   ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+   TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+   HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+   NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+   POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package util
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// ConcurrentMap is the API shared by the concurrent string map implementations in
+// this package, so code can be written against it and the implementation picked to
+// suit the workload.
+type ConcurrentMap interface {
+	ForEach(do func(string, string))
+	Length() int
+	Add(key string, value string) error
+	Del(key string) error
+	Get(key string) (string, error)
+	Set(key string, value string) error
+	Exists(key string) bool
+}
+
+var (
+	_ ConcurrentMap = (*ConcurrentMapString)(nil)
+	_ ConcurrentMap = (*ShardedConcurrentMapString)(nil)
+	_ ConcurrentMap = (*ExpiringMapString)(nil)
+	_ ConcurrentMap = (*LRUMapString)(nil)
+	_ ConcurrentMap = (*CopyOnWriteMapString)(nil)
+	_ ConcurrentMap = (*SyncMapString)(nil)
+)
+
+// SyncMapString is a ConcurrentMap backed by a sync.Map, which can outperform a
+// mutex-guarded map when keys are written once and read many times, or when many
+// goroutines work on disjoint sets of keys.
+type SyncMapString struct {
+	data   sync.Map
+	length atomic.Int64
+}
+
+// NewConcurrentMapSyncBacked initializes and returns a pointer to a new SyncMapString instance.
+func NewConcurrentMapSyncBacked() *SyncMapString {
+	return &SyncMapString{}
+}
+
+// ForEach will call the provided function for each entry in the SyncMapString.
+// As with sync.Map.Range, it is not a consistent snapshot if the map is being written to.
+func (m *SyncMapString) ForEach(do func(string, string)) {
+	m.data.Range(func(key, val any) bool {
+		do(key.(string), val.(string))
+		return true
+	})
+}
+
+// Length returns the number of entries in the map. It is counted from the result
+// of each Add and Del just after they change the map, so while they run concurrently
+// it is approximate and may briefly lag the contents, but it never drifts from them.
+func (m *SyncMapString) Length() int {
+	return int(max(m.length.Load(), 0)) // A Del can be counted before the Add it undoes.
+}
+
+// Add is used to add a key/value to the map.
+// Returns a KeyError wrapping ErrKeyExists if the key already exists.
+func (m *SyncMapString) Add(key string, value string) error {
+	if _, loaded := m.data.LoadOrStore(key, value); loaded {
+		return &KeyError{Op: "SyncMapString: Cannot add map entry", Key: key, Err: ErrKeyExists}
+	}
+
+	m.length.Add(1)
+	return nil
+}
+
+// Del is used to remove a key/value from the map.
+// Returns a KeyError wrapping ErrKeyNotFound if the key does not exist.
+func (m *SyncMapString) Del(key string) error {
+	if _, loaded := m.data.LoadAndDelete(key); !loaded {
+		return &KeyError{Op: "SyncMapString: Cannot delete map entry", Key: key, Err: ErrKeyNotFound}
+	}
+
+	m.length.Add(-1)
+	return nil
+}
+
+// Get is used to get a key/value from the map.
+// Returns a KeyError wrapping ErrKeyNotFound if the key does not exist.
+func (m *SyncMapString) Get(key string) (string, error) {
+	v, exists := m.data.Load(key)
+
+	if !exists {
+		return "", &KeyError{Op: "SyncMapString: Cannot get map value", Key: key, Err: ErrKeyNotFound}
+	}
+
+	return v.(string), nil
+}
+
+// Set is used to change an existing key/value in the map.
+// Returns a KeyError wrapping ErrKeyNotFound if the key does not exist.
+func (m *SyncMapString) Set(key string, value string) error {
+	for {
+		old, exists := m.data.Load(key)
+
+		if !exists {
+			return &KeyError{Op: "SyncMapString: Cannot set map value", Key: key, Err: ErrKeyNotFound}
+		}
+
+		// Only replace the value we saw, so a concurrent Del can't be undone.
+		if m.data.CompareAndSwap(key, old, value) {
+			return nil
+		}
+	}
+}
+
+// Exists is used by external callers to check if a value
+// exists in the map and returns a boolean with the result.
+func (m *SyncMapString) Exists(key string) bool {
+	_, exists := m.data.Load(key)
+	return exists
+}
@@ -0,0 +1,69 @@
+/*
+   Copyright (c) 2020, btnmasher
+   All rights reserved.
+
+   Redistribution and use in source and binary forms, with or without modification, are permitted provided that
+   the following conditions are met:
+
+   1. Redistributions of source code must retain the above copyright notice, this list of conditions and the
+      following disclaimer.
+
+   2. Redistributions in binary form must reproduce the above copyright notice, this list of conditions and
+      the following disclaimer in the documentation and/or other materials provided with the distribution.
+
+   3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse or
+      promote products derived from this software without specific prior written permission.
+
+   THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED
+   WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+   PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR
+   ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+   TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+   HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+   NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+   POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package util
+
+import "sync"
+
+// SyncPool is a typed wrapper around a sync.Pool, with the same semantics as Pool:
+// Get falls back to constructing an object when the pool is empty, and Put resets
+// the object before it is kept. Like sync.Pool, it has no fixed size and the garbage
+// collector is free to release idle objects.
+type SyncPool[T any] struct {
+	pool  sync.Pool
+	reset func(T)
+}
+
+// NewSyncPool creates a new sync.Pool backed object pool. The new function constructs
+// an object when the pool is empty and must not be nil, and the optional reset function
+// is called on each object as it is put back, to clear it for the next user.
+func NewSyncPool[T any](new func() T, reset func(T)) *SyncPool[T] {
+	if new == nil {
+		panic("util: NewSyncPool requires a constructor")
+	}
+
+	return &SyncPool[T]{
+		pool: sync.Pool{
+			New: func() any {
+				return new()
+			},
+		},
+		reset: reset,
+	}
+}
+
+// Get takes an object from the pool, constructing one if the pool is empty.
+func (pool *SyncPool[T]) Get() T {
+	return pool.pool.Get().(T)
+}
+
+// Put resets an object and returns it to the pool.
+func (pool *SyncPool[T]) Put(item T) {
+	if pool.reset != nil {
+		pool.reset(item)
+	}
+	pool.pool.Put(item)
+}
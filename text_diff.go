@@ -0,0 +1,251 @@
+/*
+   Copyright (c) 2020, btnmasher
+   All rights reserved.
+
+   Redistribution and use in source and binary forms, with or without modification, are permitted provided that
+   the following conditions are met:
+
+   1. Redistributions of source code must retain the above copyright notice, this list of conditions and the
+      following disclaimer.
+
+   2. Redistributions in binary form must reproduce the above copyright notice, this list of conditions and
+      the following disclaimer in the documentation and/or other materials provided with the distribution.
+
+   3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse or
+      promote products derived from this software without specific prior written permission.
+
+   THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED
+   WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+   PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR
+   ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+   TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+   HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+   NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+   POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package util
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DiffOp says what happened to a line in a DiffLine.
+type DiffOp int
+
+const (
+	DiffEqual  DiffOp = iota // The line is in both strings.
+	DiffDelete               // The line is only in the old string.
+	DiffInsert               // The line is only in the new string.
+)
+
+// String returns the prefix of the operation in a unified diff.
+func (op DiffOp) String() string {
+	switch op {
+	case DiffDelete:
+		return "-"
+	case DiffInsert:
+		return "+"
+	default:
+		return " "
+	}
+}
+
+// DiffLine is one line of the difference between two strings.
+type DiffLine struct {
+	Op      DiffOp
+	Text    string // The line, without its newline.
+	OldLine int    // 1-based line number in the old string, or 0 for an insertion.
+	NewLine int    // 1-based line number in the new string, or 0 for a deletion.
+}
+
+// diffContext is the number of unchanged lines shown around each change by Diff.
+const diffContext = 3
+
+// DiffLines compares a and b line by line and returns every line of both, in order,
+// marked as kept, deleted or inserted. The edit is as short as possible, found with
+// the linear space variant of Myers' algorithm, which is fast when the strings are
+// mostly the same. Its time grows with the number of lines times the number of
+// changes, while its memory only grows with the number of lines.
+func DiffLines(a, b string) []DiffLine {
+	return diffLines(nil, splitLines(a), splitLines(b), 0, 0)
+}
+
+// diffLines appends the lines of the shortest edit from a to b to lines, numbering
+// them as if a and b started after line oldStart and newStart of their strings.
+func diffLines(lines []DiffLine, a, b []string, oldStart, newStart int) []DiffLine {
+	for len(a) > 0 && len(b) > 0 && a[0] == b[0] {
+		oldStart, newStart = oldStart+1, newStart+1
+		lines = append(lines, DiffLine{Op: DiffEqual, Text: a[0], OldLine: oldStart, NewLine: newStart})
+		a, b = a[1:], b[1:]
+	}
+
+	// The common suffix is appended after the lines in between.
+	same := 0
+	for same < len(a) && same < len(b) && a[len(a)-1-same] == b[len(b)-1-same] {
+		same++
+	}
+	suffix := a[len(a)-same:]
+	a, b = a[:len(a)-same], b[:len(b)-same]
+
+	switch {
+	case len(a) == 0:
+		for i, text := range b {
+			lines = append(lines, DiffLine{Op: DiffInsert, Text: text, NewLine: newStart + i + 1})
+		}
+	case len(b) == 0:
+		for i, text := range a {
+			lines = append(lines, DiffLine{Op: DiffDelete, Text: text, OldLine: oldStart + i + 1})
+		}
+	default:
+		// Neither the first nor the last lines match, so the edit is at least two long
+		// and both halves around its middle snake are smaller than the whole.
+		x, y, u, v := middleSnake(a, b)
+		lines = diffLines(lines, a[:x], b[:y], oldStart, newStart)
+		for i := 0; i < u-x; i++ {
+			lines = append(lines, DiffLine{Op: DiffEqual, Text: a[x+i], OldLine: oldStart + x + i + 1, NewLine: newStart + y + i + 1})
+		}
+		lines = diffLines(lines, a[u:], b[v:], oldStart+u, newStart+v)
+	}
+
+	oldStart, newStart = oldStart+len(a), newStart+len(b)
+	for i, text := range suffix {
+		lines = append(lines, DiffLine{Op: DiffEqual, Text: text, OldLine: oldStart + i + 1, NewLine: newStart + i + 1})
+	}
+
+	return lines
+}
+
+// middleSnake finds the run of matching lines in the middle of a shortest edit from
+// a to b, by searching forwards from the start and backwards from the end until the
+// two searches meet. It returns the run as a[x:u], which matches b[y:v].
+func middleSnake(a, b []string) (x, y, u, v int) {
+	n, m := len(a), len(b)
+	delta := n - m
+	odd := delta%2 != 0
+	half := (n + m + 1) / 2
+	offset := half + 1
+
+	// The furthest x reached on each diagonal, counted from the start going forwards
+	// and from the end going backwards. Diagonal k going backwards is delta-k going forwards.
+	forward := make([]int, 2*offset+1)
+	backward := make([]int, 2*offset+1)
+
+	for d := 0; d <= half; d++ {
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || k != d && forward[offset+k-1] < forward[offset+k+1] {
+				x = forward[offset+k+1] // Down, an insertion.
+			} else {
+				x = forward[offset+k-1] + 1 // Right, a deletion.
+			}
+			y := x - k
+			startX, startY := x, y
+			for x < n && y < m && a[x] == b[y] {
+				x, y = x+1, y+1
+			}
+			forward[offset+k] = x
+
+			if back := delta - k; odd && back >= -(d-1) && back <= d-1 && x+backward[offset+back] >= n {
+				return startX, startY, x, y
+			}
+		}
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || k != d && backward[offset+k-1] < backward[offset+k+1] {
+				x = backward[offset+k+1]
+			} else {
+				x = backward[offset+k-1] + 1
+			}
+			y := x - k
+			startX, startY := x, y
+			for x < n && y < m && a[n-1-x] == b[m-1-y] {
+				x, y = x+1, y+1
+			}
+			backward[offset+k] = x
+
+			if front := delta - k; !odd && front >= -d && front <= d && forward[offset+front]+x >= n {
+				return n - x, m - y, n - startX, m - startY
+			}
+		}
+	}
+
+	panic("middleSnake: searches never met") // Unreachable, they meet within half steps.
+}
+
+// Diff compares a and b line by line and returns the changes as a unified diff,
+// with three lines of context around each change, or "" if they are the same.
+func Diff(a, b string) string {
+	lines := DiffLines(a, b)
+
+	var out strings.Builder
+	for i := 0; i < len(lines); {
+		if lines[i].Op == DiffEqual {
+			i++
+			continue
+		}
+
+		// Grow the hunk until the next change is too far away to share its context.
+		start := max(i-diffContext, 0)
+		end := i
+		for j := i; j < len(lines) && j <= end+2*diffContext; j++ {
+			if lines[j].Op != DiffEqual {
+				end = j
+			}
+		}
+		end = min(end+diffContext+1, len(lines))
+
+		if out.Len() == 0 {
+			out.WriteString("--- a\n+++ b\n")
+		}
+		writeHunk(&out, lines[start:end])
+		i = end
+	}
+
+	return out.String()
+}
+
+// writeHunk writes a hunk of a unified diff, with its header.
+func writeHunk(out *strings.Builder, hunk []DiffLine) {
+	oldStart, newStart, oldCount, newCount := 0, 0, 0, 0 // An empty side starts at 0, as in diff.
+	for _, line := range hunk {
+		if line.OldLine > 0 {
+			if oldCount == 0 {
+				oldStart = line.OldLine
+			}
+			oldCount++
+		}
+		if line.NewLine > 0 {
+			if newCount == 0 {
+				newStart = line.NewLine
+			}
+			newCount++
+		}
+	}
+
+	fmt.Fprintf(out, "@@ -%s +%s @@\n", hunkRange(oldStart, oldCount), hunkRange(newStart, newCount))
+	for _, line := range hunk {
+		out.WriteString(line.Op.String())
+		out.WriteString(line.Text)
+		out.WriteByte('\n')
+	}
+}
+
+// hunkRange formats the start and length of one side of a hunk header.
+func hunkRange(start, count int) string {
+	if count == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// splitLines splits s into its lines, without their newlines.
+// A final newline does not start another line.
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
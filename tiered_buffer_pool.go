@@ -0,0 +1,121 @@
+/*
+   Copyright (c) 2020, btnmasher
+   All rights reserved.
+
+   Redistribution and use in source and binary forms, with or without modification, are permitted provided that
+   the following conditions are met:
+
+   1. Redistributions of source code must retain the above copyright notice, this list of conditions and the
+      following disclaimer.
+
+   2. Redistributions in binary form must reproduce the above copyright notice, this list of conditions and
+      the following disclaimer in the documentation and/or other materials provided with the distribution.
+
+   3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse or
+      promote products derived from this software without specific prior written permission.
+
+   THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED
+   WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+   PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR
+   ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+   TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+   HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+   NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+   POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package util
+
+import (
+	"bytes"
+	"sort"
+)
+
+// bufferTier is one size class of a TieredBufferPool.
+type bufferTier struct {
+	size int
+	pool *BufferPool
+}
+
+// TieredBufferPool keeps separate BufferPools for Buffers of different sizes, so that
+// callers which know roughly how much they will write get a Buffer that already fits,
+// and small writes don't tie up large Buffers.
+type TieredBufferPool struct {
+	tiers []bufferTier
+}
+
+var _ BufferSource = (*TieredBufferPool)(nil)
+
+// NewTieredBufferPool creates a new TieredBufferPool with a tier for each of the given
+// sizes, each holding at most max Buffers. Recycled Buffers larger than twice the
+// largest size are dropped rather than pinning their memory.
+func NewTieredBufferPool(max int, sizes ...int) *TieredBufferPool {
+	sizes = append([]int(nil), sizes...)
+	sort.Ints(sizes)
+
+	pool := &TieredBufferPool{}
+	for _, size := range sizes {
+		if size <= 0 || (len(pool.tiers) > 0 && pool.tiers[len(pool.tiers)-1].size == size) {
+			continue
+		}
+		pool.tiers = append(pool.tiers, bufferTier{size: size, pool: NewBufferPool(max)})
+	}
+
+	if len(pool.tiers) == 0 {
+		pool.tiers = append(pool.tiers, bufferTier{size: 0, pool: NewBufferPool(max)})
+	}
+
+	top := pool.tiers[len(pool.tiers)-1]
+	top.pool.MaxCapacity = 2 * top.size
+
+	return pool
+}
+
+// Warmup fills each tier with the specified number of Buffers of its size.
+func (pool *TieredBufferPool) Warmup(num int) {
+	for _, tier := range pool.tiers {
+		tier.pool.Warmup(num, tier.size)
+	}
+}
+
+// New takes a Buffer from the smallest tier.
+func (pool *TieredBufferPool) New() *bytes.Buffer {
+	return pool.tiers[0].pool.New()
+}
+
+// NewSized takes a Buffer from the smallest tier whose size covers n bytes,
+// growing it if needed so that it can hold n bytes without reallocating.
+func (pool *TieredBufferPool) NewSized(n int) *bytes.Buffer {
+	tier := pool.tiers[len(pool.tiers)-1]
+	for _, t := range pool.tiers {
+		if t.size >= n {
+			tier = t
+			break
+		}
+	}
+
+	buf := tier.pool.New()
+	buf.Grow(n)
+	return buf
+}
+
+// Recycle returns a Buffer to the largest tier whose size it can hold.
+func (pool *TieredBufferPool) Recycle(buf *bytes.Buffer) {
+	tier := pool.tiers[0]
+	for _, t := range pool.tiers[1:] {
+		if buf.Cap() < t.size {
+			break
+		}
+		tier = t
+	}
+
+	tier.pool.Recycle(buf)
+}
+
+// Close closes each of the tiers, passing every Buffer waiting in them to release
+// (if not nil), see BufferPool.Close.
+func (pool *TieredBufferPool) Close(release func(*bytes.Buffer)) {
+	for _, tier := range pool.tiers {
+		tier.pool.Close(release)
+	}
+}
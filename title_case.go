@@ -0,0 +1,113 @@
+/*
+   Copyright (c) 2020, btnmasher
+   All rights reserved.
+
+   Redistribution and use in source and binary forms, with or without modification, are permitted provided that
+   the following conditions are met:
+
+   1. Redistributions of source code must retain the above copyright notice, this list of conditions and the
+      following disclaimer.
+
+   2. Redistributions in binary form must reproduce the above copyright notice, this list of conditions and
+      the following disclaimer in the documentation and/or other materials provided with the distribution.
+
+   3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse or
+      promote products derived from this software without specific prior written permission.
+
+   THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED
+   WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+   PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR
+   ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+   TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+   HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+   NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+   POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package util
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultSmallWords are the words TitleCase leaves in lower case: English articles,
+// short conjunctions and short prepositions.
+var DefaultSmallWords = []string{
+	"a", "an", "and", "as", "at", "but", "by", "en", "for", "if", "in", "nor",
+	"of", "on", "or", "per", "the", "to", "via", "vs",
+}
+
+// TitleCase capitalizes the first letter of each word in s, except for the
+// DefaultSmallWords, which are put in lower case. It is the same as
+// TitleCaseWith(s, DefaultSmallWords).
+func TitleCase(s string) string {
+	return TitleCaseWith(s, DefaultSmallWords)
+}
+
+// TitleCaseWith capitalizes the first letter of each word in s, leaving the rest of
+// the word alone so acronyms such as "NASA" survive. The given small words are put in
+// lower case instead, except as the first or last word, or after a colon, full stop,
+// question or exclamation mark, where a new phrase starts. Whitespace is kept as it is.
+func TitleCaseWith(s string, smallWords []string) string {
+	small := make(map[string]struct{}, len(smallWords))
+	for _, word := range smallWords {
+		small[strings.ToLower(word)] = struct{}{}
+	}
+
+	type span struct{ start, end int }
+	var words []span
+	start := -1
+	for i, r := range s {
+		switch {
+		case unicode.IsSpace(r) && start >= 0:
+			words = append(words, span{start, i})
+			start = -1
+		case !unicode.IsSpace(r) && start < 0:
+			start = i
+		}
+	}
+	if start >= 0 {
+		words = append(words, span{start, len(s)})
+	}
+
+	var out strings.Builder
+	last := 0
+	boundary := true // Whether the next word starts a phrase.
+
+	for n, w := range words {
+		word := s[w.start:w.end]
+		out.WriteString(s[last:w.start])
+		last = w.end
+
+		bare := strings.ToLower(strings.TrimFunc(word, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		}))
+
+		if _, ok := small[bare]; ok && !boundary && n+1 < len(words) {
+			out.WriteString(strings.ToLower(word))
+		} else {
+			out.WriteString(capitalizeFirstLetter(word))
+		}
+
+		end, _ := utf8.DecodeLastRuneInString(word)
+		boundary = strings.ContainsRune(":.?!", end)
+	}
+	out.WriteString(s[last:])
+
+	return out.String()
+}
+
+// capitalizeFirstLetter puts the first letter in word in upper case.
+func capitalizeFirstLetter(word string) string {
+	for i, r := range word {
+		if unicode.IsLetter(r) {
+			return word[:i] + string(unicode.ToTitle(r)) + word[i+utf8.RuneLen(r):]
+		}
+		if unicode.IsDigit(r) {
+			break // Leave words such as "3rd" alone.
+		}
+	}
+	return word
+}
@@ -0,0 +1,68 @@
+/*
+   Copyright (c) 2020, btnmasher
+   All rights reserved.
+
+   Redistribution and use in source and binary forms, with or without modification, are permitted provided that
+   the following conditions are met:
+
+   1. Redistributions of source code must retain the above copyright notice, this list of conditions and the
+      following disclaimer.
+
+   2. Redistributions in binary form must reproduce the above copyright notice, this list of conditions and
+      the following disclaimer in the documentation and/or other materials provided with the distribution.
+
+   3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse or
+      promote products derived from this software without specific prior written permission.
+
+   THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED
+   WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+   PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR
+   ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+   TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+   HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+   NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+   POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package util
+
+import "unicode/utf8"
+
+// Truncate shortens s to its first max runes and appends suffix, such as "…", if
+// anything was cut off. Strings of max runes or fewer are returned unchanged.
+// Multi-byte characters are never split.
+func Truncate(s string, max int, suffix string) string {
+	if max < 0 {
+		max = 0
+	}
+
+	runes := 0
+	for i := range s {
+		if runes == max {
+			return s[:i] + suffix
+		}
+		runes++
+	}
+
+	return s
+}
+
+// AbbreviateMiddle shortens s to at most max runes by replacing its middle with sep,
+// such as "verylongt…/last.go", keeping the beginning and end of s visible. The odd rune,
+// if any, goes to the beginning. Strings of max runes or fewer are returned unchanged,
+// and if sep doesn't leave room for any of s, s is simply cut to max runes.
+func AbbreviateMiddle(s string, max int, sep string) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+
+	keep := max - utf8.RuneCountInString(sep)
+	if keep <= 0 {
+		return Truncate(s, max, "")
+	}
+
+	head := (keep + 1) / 2
+	tail := keep - head
+	return string(runes[:head]) + sep + string(runes[len(runes)-tail:])
+}
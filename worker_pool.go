@@ -0,0 +1,260 @@
+/*
+   Copyright (c) 2020, btnmasher
+   All rights reserved.
+
+   Redistribution and use in source and binary forms, with or without modification, are permitted provided that
+   the following conditions are met:
+
+   1. Redistributions of source code must retain the above copyright notice, this list of conditions and the
+      following disclaimer.
+
+   2. Redistributions in binary form must reproduce the above copyright notice, this list of conditions and
+      the following disclaimer in the documentation and/or other materials provided with the distribution.
+
+   3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse or
+      promote products derived from this software without specific prior written permission.
+
+   THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED
+   WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+   PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR
+   ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+   TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+   HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+   NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+   POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package util
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+)
+
+// PanicError is returned by WorkerPool.SubmitWait when the task panicked.
+type PanicError struct {
+	Value any    // The value passed to panic.
+	Stack []byte // The stack of the panicking goroutine.
+}
+
+// Error returns the description of the panic.
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("WorkerPool: task panicked: %v", e.Value)
+}
+
+// OverflowPolicy decides what WorkerPool.Submit does with a task when the queue is full.
+type OverflowPolicy int
+
+// The available overflow policies.
+const (
+	OverflowBlock      OverflowPolicy = iota // Wait for room in the queue.
+	OverflowDrop                             // Discard the task.
+	OverflowError                            // Return ErrQueueFull.
+	OverflowCallerRuns                       // Run the task on the submitting goroutine.
+)
+
+// errDropped is returned by submit when a task was discarded under OverflowDrop.
+var errDropped = errors.New("task dropped")
+
+// WorkerPool runs submitted tasks on a fixed number of long-lived goroutines,
+// taking them from a queue in the order they were submitted. A panicking task
+// is recovered so it doesn't take its worker, or the process, down with it.
+type WorkerPool struct {
+	tasks chan func()
+
+	// PanicHandler, if not nil, is called with the recovered value and stack of any task
+	// which panics. It must be set before tasks are submitted.
+	PanicHandler func(recovered any, stack []byte)
+
+	// Overflow is the policy applied by Submit when the queue is full, which keeps
+	// bursty producers from piling up unbounded work. It must be set before tasks
+	// are submitted. The default is OverflowBlock.
+	Overflow OverflowPolicy
+
+	dropped atomic.Uint64
+
+	mu      sync.RWMutex // Guards closed and senders.Add against Stop.
+	closed  bool
+	senders sync.WaitGroup // Submits past the closed check, which Stop waits for before closing tasks.
+	workers sync.WaitGroup
+
+	pendingMu sync.Mutex
+	pending   int // Tasks submitted but not yet finished.
+	idle      *sync.Cond
+}
+
+// NewWorkerPool creates a new WorkerPool and starts its workers. Up to queue tasks
+// may wait for a worker before Submit blocks.
+func NewWorkerPool(workers, queue int) *WorkerPool {
+	if workers < 1 {
+		workers = 1
+	}
+	if queue < 0 {
+		queue = 0
+	}
+
+	pool := &WorkerPool{
+		tasks: make(chan func(), queue),
+	}
+	pool.idle = sync.NewCond(&pool.pendingMu)
+
+	pool.workers.Add(workers)
+	for i := 0; i < workers; i++ {
+		go pool.work()
+	}
+
+	return pool
+}
+
+// work runs tasks until the queue is closed and empty.
+func (pool *WorkerPool) work() {
+	defer pool.workers.Done()
+
+	for task := range pool.tasks {
+		pool.run(task)
+	}
+}
+
+// run runs a single task, recovering any panic, and marks it finished.
+func (pool *WorkerPool) run(task func()) {
+	defer pool.finish()
+	defer func() {
+		if r := recover(); r != nil && pool.PanicHandler != nil {
+			pool.PanicHandler(r, debug.Stack())
+		}
+	}()
+
+	task()
+}
+
+// finish marks a task as finished, waking Drain once none are pending.
+func (pool *WorkerPool) finish() {
+	pool.pendingMu.Lock()
+	pool.pending--
+	if pool.pending == 0 {
+		pool.idle.Broadcast()
+	}
+	pool.pendingMu.Unlock()
+}
+
+// Submit queues a task to be run by a worker. If the queue is full, the pool's Overflow
+// policy decides whether to wait for room, drop the task, return ErrQueueFull or run the
+// task immediately on the calling goroutine. Returns ErrPoolClosed if the pool has been stopped.
+func (pool *WorkerPool) Submit(task func()) error {
+	if err := pool.submit(task); err != errDropped {
+		return err
+	}
+	return nil
+}
+
+// submit is the implementation of Submit, which returns errDropped for dropped tasks.
+func (pool *WorkerPool) submit(task func()) error {
+	// The lock is only held for the check, never while waiting for room in the queue,
+	// so a task which submits more tasks can't hold up Stop, nor be held up by it.
+	pool.mu.RLock()
+	if pool.closed {
+		pool.mu.RUnlock()
+		return ErrPoolClosed
+	}
+	pool.senders.Add(1)
+	pool.mu.RUnlock()
+
+	defer pool.senders.Done()
+
+	pool.pendingMu.Lock()
+	pool.pending++
+	pool.pendingMu.Unlock()
+
+	if pool.Overflow == OverflowBlock {
+		pool.tasks <- task
+		return nil
+	}
+
+	select {
+	case pool.tasks <- task:
+		return nil
+	default:
+	}
+
+	switch pool.Overflow {
+	case OverflowCallerRuns:
+		pool.run(task)
+		return nil
+	case OverflowDrop:
+		pool.dropped.Add(1)
+		pool.finish()
+		return errDropped
+	default:
+		pool.finish()
+		return ErrQueueFull
+	}
+}
+
+// Dropped returns the number of tasks discarded under OverflowDrop.
+func (pool *WorkerPool) Dropped() uint64 {
+	return pool.dropped.Load()
+}
+
+// SubmitWait queues a task and waits for it to finish. Returns ErrPoolClosed if the
+// pool has been stopped, or a *PanicError if the task panicked. A task which can't be
+// queued under OverflowDrop or OverflowError returns ErrQueueFull.
+func (pool *WorkerPool) SubmitWait(task func()) error {
+	var perr *PanicError
+	done := make(chan struct{})
+
+	err := pool.submit(func() {
+		defer close(done)
+		defer func() {
+			if r := recover(); r != nil {
+				perr = &PanicError{Value: r, Stack: debug.Stack()}
+				panic(r) // Let the worker report it as usual.
+			}
+		}()
+
+		task()
+	})
+	if err == errDropped {
+		return ErrQueueFull
+	}
+	if err != nil {
+		return err
+	}
+
+	<-done
+
+	if perr != nil {
+		return perr
+	}
+	return nil
+}
+
+// Drain waits until every task submitted so far has finished. The pool keeps
+// running and accepting tasks afterwards.
+func (pool *WorkerPool) Drain() {
+	pool.pendingMu.Lock()
+	defer pool.pendingMu.Unlock()
+
+	for pool.pending > 0 {
+		pool.idle.Wait()
+	}
+}
+
+// Stop stops the pool from accepting tasks, waits for the queued and running
+// tasks to finish, and then stops the workers. It is safe to call more than once.
+func (pool *WorkerPool) Stop() {
+	pool.mu.Lock()
+	closing := !pool.closed
+	pool.closed = true
+	pool.mu.Unlock()
+
+	if closing {
+		// The workers keep taking tasks, so Submits already waiting for room finish.
+		pool.senders.Wait()
+		close(pool.tasks)
+	}
+
+	pool.workers.Wait()
+}
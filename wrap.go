@@ -0,0 +1,94 @@
+/*
+   Copyright (c) 2020, btnmasher
+   All rights reserved.
+
+   Redistribution and use in source and binary forms, with or without modification, are permitted provided that
+   the following conditions are met:
+
+   1. Redistributions of source code must retain the above copyright notice, this list of conditions and the
+      following disclaimer.
+
+   2. Redistributions in binary form must reproduce the above copyright notice, this list of conditions and
+      the following disclaimer in the documentation and/or other materials provided with the distribution.
+
+   3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse or
+      promote products derived from this software without specific prior written permission.
+
+   THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED
+   WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+   PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR
+   ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+   TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+   HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+   NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+   POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package util
+
+import "strings"
+
+// WrapText wraps s at word boundaries so that no line is longer than width runes,
+// counting its indentation. Each line of s is wrapped as a paragraph of its own,
+// its first line prefixed by indent and the lines it wraps onto by subsequentIndent.
+// Runs of whitespace between words are collapsed to one space, and a word too long
+// to fit on a line by itself is broken across lines. Blank lines are kept.
+// A width of zero or less means lines are only indented, never wrapped.
+func WrapText(s string, width int, indent, subsequentIndent string) string {
+	return wrapText(s, width, indent, subsequentIndent, runeMeasure)
+}
+
+// wrapText implements WrapText, measuring the text with measure.
+func wrapText(s string, width int, indent, subsequentIndent string, measure textMeasure) string {
+	var out strings.Builder
+	length := measure.length
+
+	for n, paragraph := range strings.Split(s, "\n") {
+		if n > 0 {
+			out.WriteByte('\n')
+		}
+
+		words := strings.Fields(paragraph)
+		if len(words) == 0 {
+			continue
+		}
+
+		prefix := indent
+		line := 0 // Length of the current line so far, or zero before its first word.
+
+		for _, word := range words {
+			wordlen := length(word)
+
+			if line > 0 && (width <= 0 || line+1+wordlen <= width) {
+				out.WriteByte(' ')
+				out.WriteString(word)
+				line += 1 + wordlen
+				continue
+			}
+
+			if line > 0 {
+				out.WriteByte('\n')
+				prefix = subsequentIndent
+			}
+
+			// Break up a word which can't fit on a line of its own.
+			room := max(width-length(prefix), 1)
+			for width > 0 && wordlen > room {
+				piece := splitByLength(word, room, measure)[0]
+				out.WriteString(prefix)
+				out.WriteString(piece)
+				out.WriteByte('\n')
+				prefix = subsequentIndent
+				room = max(width-length(prefix), 1)
+				word = word[len(piece):]
+				wordlen = length(word)
+			}
+
+			out.WriteString(prefix)
+			out.WriteString(word)
+			line = length(prefix) + wordlen
+		}
+	}
+
+	return out.String()
+}
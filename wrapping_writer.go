@@ -0,0 +1,127 @@
+/*
+   Copyright (c) 2020, btnmasher
+   All rights reserved.
+
+   Redistribution and use in source and binary forms, with or without modification, are permitted provided that
+   the following conditions are met:
+
+   1. Redistributions of source code must retain the above copyright notice, this list of conditions and the
+      following disclaimer.
+
+   2. Redistributions in binary form must reproduce the above copyright notice, this list of conditions and
+      the following disclaimer in the documentation and/or other materials provided with the distribution.
+
+   3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse or
+      promote products derived from this software without specific prior written permission.
+
+   THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED
+   WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+   PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR
+   ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+   TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+   HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+   NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+   POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package util
+
+import (
+	"io"
+	"unicode/utf8"
+)
+
+// WrappingWriter is an io.Writer which wraps the text written through it at word
+// boundaries, so that no line is longer than width runes, as it is written. Lines
+// it wraps onto start with the hanging indent, while lines started by a newline in
+// the text do not. A word too long to fit on a line by itself is broken across lines.
+// The last word is held back until whitespace follows it, so call Flush once done.
+// Like bufio.Writer, a WrappingWriter is not safe for concurrent use.
+type WrappingWriter struct {
+	w      io.Writer
+	width  int
+	indent string
+
+	col     int    // Runes written on the current line.
+	spaces  []byte // Whitespace after the last word, written only if another word follows on the line.
+	word    []byte // The word being written.
+	wordLen int    // Runes in word.
+	err     error
+}
+
+var _ io.Writer = (*WrappingWriter)(nil)
+
+// NewWrappingWriter creates a new WrappingWriter wrapping text at width runes before
+// writing it to w, and starting every line it wraps onto with indent.
+func NewWrappingWriter(w io.Writer, width int, indent string) *WrappingWriter {
+	return &WrappingWriter{
+		w:      w,
+		width:  max(width, 1),
+		indent: indent,
+	}
+}
+
+// Write wraps p and writes it out, apart from the word it ends with, if any.
+// Returns the first error from the underlying writer, after which nothing more is written.
+func (ww *WrappingWriter) Write(p []byte) (int, error) {
+	room := max(ww.width-utf8.RuneCountInString(ww.indent), 1) // The most a word can take of a line.
+
+	for _, c := range p {
+		switch c {
+		case '\n':
+			ww.writeWord()
+			ww.spaces = ww.spaces[:0]
+			ww.write([]byte{'\n'})
+			ww.col = 0
+		case ' ', '\t':
+			ww.writeWord()
+			ww.spaces = append(ww.spaces, c)
+		default:
+			if utf8.RuneStart(c) {
+				if ww.wordLen == room {
+					ww.writeWord() // Break up a word which can't fit on a line of its own.
+				}
+				ww.wordLen++
+			}
+			ww.word = append(ww.word, c)
+		}
+	}
+
+	return len(p), ww.err
+}
+
+// Flush writes out the word held back at the end of the text written so far.
+func (ww *WrappingWriter) Flush() error {
+	ww.writeWord()
+	return ww.err
+}
+
+// writeWord writes out the pending word, starting a new line first if it doesn't fit.
+func (ww *WrappingWriter) writeWord() {
+	if len(ww.word) == 0 {
+		return
+	}
+
+	if ww.col > 0 && ww.col+len(ww.spaces)+ww.wordLen > ww.width {
+		ww.write([]byte{'\n'})
+		ww.write([]byte(ww.indent))
+		ww.col = utf8.RuneCountInString(ww.indent)
+	} else {
+		ww.write(ww.spaces)
+		ww.col += len(ww.spaces)
+	}
+
+	ww.write(ww.word)
+	ww.col += ww.wordLen
+
+	ww.spaces = ww.spaces[:0]
+	ww.word = ww.word[:0]
+	ww.wordLen = 0
+}
+
+// write writes b to the underlying writer, unless it has already failed.
+func (ww *WrappingWriter) write(b []byte) {
+	if ww.err == nil && len(b) > 0 {
+		_, ww.err = ww.w.Write(b)
+	}
+}
@@ -0,0 +1,103 @@
+/*
+   Copyright (c) 2020, btnmasher
+   All rights reserved.
+
+   Redistribution and use in source and binary forms, with or without modification, are permitted provided that
+   the following conditions are met:
+
+   1. Redistributions of source code must retain the above copyright notice, this list of conditions and the
+      following disclaimer.
+
+   2. Redistributions in binary form must reproduce the above copyright notice, this list of conditions and
+      the following disclaimer in the documentation and/or other materials provided with the distribution.
+
+   3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse or
+      promote products derived from this software without specific prior written permission.
+
+   THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED
+   WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+   PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR
+   ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+   TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+   HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+   NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+   POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package util
+
+import (
+	"compress/zlib"
+	"io"
+)
+
+// ZlibPool is a pool of zlib.Writers of a fixed compression level, and of zlib readers.
+type ZlibPool struct {
+	writers *Pool[*zlib.Writer]
+	readers *Pool[io.ReadCloser]
+}
+
+// NewZlibPool creates a new pool holding at most max zlib.Writers and max zlib readers.
+// The writers compress at the given level, which is one of the zlib package's levels.
+// Returns an error if the level is invalid.
+func NewZlibPool(max, level int) (*ZlibPool, error) {
+	if _, err := zlib.NewWriterLevel(io.Discard, level); err != nil {
+		return nil, err
+	}
+
+	return &ZlibPool{
+		writers: NewPool(max,
+			func() *zlib.Writer {
+				zw, _ := zlib.NewWriterLevel(io.Discard, level) // Level checked above.
+				return zw
+			},
+			func(zw *zlib.Writer) { zw.Reset(io.Discard) },
+		),
+		// zlib.NewReader reads the header straight away, so readers are
+		// created by the first call to NewReader which needs one.
+		readers: NewPool(max,
+			func() io.ReadCloser { return nil },
+			nil,
+		),
+	}, nil
+}
+
+// NewWriter takes a zlib.Writer from the pool, compressing to w.
+func (pool *ZlibPool) NewWriter(w io.Writer) *zlib.Writer {
+	zw := pool.writers.New()
+	zw.Reset(w)
+	return zw
+}
+
+// RecycleWriter returns a zlib.Writer to the pool. Callers should Close the writer
+// first, as any data not yet written out is discarded.
+func (pool *ZlibPool) RecycleWriter(zw *zlib.Writer) {
+	pool.writers.Recycle(zw)
+}
+
+// NewReader takes a zlib reader from the pool, decompressing from r.
+// Returns an error if the zlib header can't be read from r.
+func (pool *ZlibPool) NewReader(r io.Reader) (io.ReadCloser, error) {
+	zr := pool.readers.New()
+	if zr == nil {
+		return zlib.NewReader(r)
+	}
+
+	if err := zr.(zlib.Resetter).Reset(r, nil); err != nil {
+		pool.readers.Recycle(zr)
+		return nil, err
+	}
+
+	return zr, nil
+}
+
+// RecycleReader returns a zlib reader taken from NewReader to the pool.
+func (pool *ZlibPool) RecycleReader(zr io.ReadCloser) {
+	pool.readers.Recycle(zr)
+}
+
+// Close empties the pool and makes later calls to RecycleWriter and RecycleReader do nothing.
+func (pool *ZlibPool) Close() {
+	pool.writers.Close(nil)
+	pool.readers.Close(nil)
+}